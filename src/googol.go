@@ -47,24 +47,32 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/lzw"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"image"
 	"image/color"
 	"image/gif"
+	"image/png"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/bits"
 	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
-	"reflect"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -76,52 +84,752 @@ const gDefaultDelay = "50"
 const gDefaultGenTotal = "10"
 const gDefaultBkColor = "white"
 const gDefaultFgColor = "black"
+const gDefaultColorMode = "binary"
+const gDefaultAgedColor = "blue"
+const gDefaultTrailLength = "8"
+const gAgeGradientLength = 64
+
+// Five palette indexes are kept for the background, the foreground, the grid, the caption and the
+// transparency of optimized frames.
+const gMaxTrailLength = 256 - 5 - gAgeGradientLength
 const gDefaultEndless = false
 const gDefaultAddr = "localhost"
 const gDefaultPort = "8080"
 const gDefaultHttps = false
+const gDefaultRule = "B3/S23"
+const gDefaultTopology = "plane"
+const gDefaultEngine = "board"
+const gDefaultViewport = "0,0"
+const gDefaultFollow = false
+const gDefaultFit = false
+const gDefaultGridColor = "silver"
+const gDefaultGridThickness = "1"
+const gDefaultCellShape = "square"
+const gDefaultCellPadding = "0"
+const gDefaultShowGeneration = false
+const gDefaultShowPopulation = false
+const gDefaultTextCorner = "top-left"
+const gDefaultTextScale = "1"
+const gDefaultOptimize = false
+const gDefaultReportSavings = false
+const gMaxGIFDelay = 0xFFFF
+const gCameraEasing = 0.25
+const gDefaultGenStep = "1"
+const gDefaultPatternAt = "0,0"
+const gMaxPatternSize = math.MaxInt32
+const gDefaultStopOnCycle = false
+const gMaxCycleFrames = 4096
+const gDefaultStatsFormat = "csv"
+const gDefaultPopulationStrip = "0"
+const gPopulationChartWidth = 640
+const gPopulationChartHeight = 240
+const gDefaultExportGen = "0"
+const gDefaultExportFormat = "rle"
+const gHashLifeMaxNodes = 1 << 21
+const gHashLifeMaxLevel = 62
+const gMaxCellCoord = 1 << 40
+const gDefaultCensusSoups = "100"
+const gDefaultCensusSoup = "16x16@0.5"
+const gDefaultCensusFormat = "table"
+const gDefaultCensusMaxGen = "20000"
+const gCensusGap = 1
+const gMaxObjectPeriod = 60
+const gDefaultIdentifyGap = "1"
+const gDefaultIdentifyFormat = "table"
+const gDefaultIdentify = false
 
 type GoogolRequest struct {
-	Proto           string
-	Addr            string
-	Port            string
-	BoardWidth      string
-	BoardHeight     string
-	GIFWidth        string
-	GIFHeight       string
-	Delay           string
-	CellSizeInPx    string
-	GenTotal        string
-	BkColor         template.HTML
-	SelectedBkColor color.Color
-	FgColor         template.HTML
-	SelectedFgColor color.Color
-	Endless         string
-	GIFData         string
-	InitialState    []string
-	Error           template.HTML
-}
-
-var gAvailColors = map[string]color.Color{"black": color.RGBA{0x00, 0x00, 0x00, 0xFF},
-	"white":   color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
-	"red":     color.RGBA{0xFF, 0x00, 0x00, 0xFF},
-	"green":   color.RGBA{0x00, 0xFF, 0x00, 0xFF},
-	"gray":    color.RGBA{0x80, 0x80, 0x80, 0xFF},
-	"blue":    color.RGBA{0x00, 0x00, 0xFF, 0xFF},
-	"cyan":    color.RGBA{0x00, 0xFF, 0xFF, 0xFF},
-	"yellow":  color.RGBA{0xFF, 0xFF, 0x00, 0xFF},
-	"magenta": color.RGBA{0xFF, 0x00, 0xFF, 0xFF}}
+	Proto              string
+	Addr               string
+	Port               string
+	BoardWidth         string
+	BoardHeight        string
+	GIFWidth           string
+	GIFHeight          string
+	Delay              string
+	CellSizeInPx       string
+	GenTotal           string
+	GenStep            string
+	PopulationStrip    string
+	Rule               string
+	Topology           template.HTML
+	SelectedTopology   string
+	Engine             template.HTML
+	SelectedEngine     string
+	Viewport           string
+	Follow             string
+	Fit                string
+	StopOnCycle        string
+	BkColor            string
+	SelectedBkColor    color.Color
+	FgColor            string
+	SelectedFgColor    color.Color
+	ColorMode          template.HTML
+	SelectedColorMode  string
+	AgedColor          string
+	SelectedAgedColor  color.Color
+	Seed               string
+	Soup               string
+	Use                string
+	SeedUsed           int64
+	TrailLength        string
+	Grid               string
+	GridColor          string
+	SelectedGridColor  color.Color
+	GridThickness      string
+	CellShape          template.HTML
+	SelectedCellShape  string
+	CellPadding        string
+	Caption            string
+	ShowGeneration     string
+	ShowPopulation     string
+	TextCorner         template.HTML
+	SelectedTextCorner string
+	TextColor          string
+	SelectedTextColor  color.Color
+	TextScale          string
+	Endless            string
+	Optimize           string
+	GIFData            string
+	RLEData            string
+	CellsData          string
+	Identify           string
+	Annotations        []lifeObject
+	InitialState       []string
+	Pattern            string
+	PatternAt          string
+	Error              template.HTML
+}
+
+var gAvailColors = map[string]color.Color{
+	"aliceblue":            color.RGBA{0xF0, 0xF8, 0xFF, 0xFF},
+	"antiquewhite":         color.RGBA{0xFA, 0xEB, 0xD7, 0xFF},
+	"aqua":                 color.RGBA{0x00, 0xFF, 0xFF, 0xFF},
+	"aquamarine":           color.RGBA{0x7F, 0xFF, 0xD4, 0xFF},
+	"azure":                color.RGBA{0xF0, 0xFF, 0xFF, 0xFF},
+	"beige":                color.RGBA{0xF5, 0xF5, 0xDC, 0xFF},
+	"bisque":               color.RGBA{0xFF, 0xE4, 0xC4, 0xFF},
+	"black":                color.RGBA{0x00, 0x00, 0x00, 0xFF},
+	"blanchedalmond":       color.RGBA{0xFF, 0xEB, 0xCD, 0xFF},
+	"blue":                 color.RGBA{0x00, 0x00, 0xFF, 0xFF},
+	"blueviolet":           color.RGBA{0x8A, 0x2B, 0xE2, 0xFF},
+	"brown":                color.RGBA{0xA5, 0x2A, 0x2A, 0xFF},
+	"burlywood":            color.RGBA{0xDE, 0xB8, 0x87, 0xFF},
+	"cadetblue":            color.RGBA{0x5F, 0x9E, 0xA0, 0xFF},
+	"chartreuse":           color.RGBA{0x7F, 0xFF, 0x00, 0xFF},
+	"chocolate":            color.RGBA{0xD2, 0x69, 0x1E, 0xFF},
+	"coral":                color.RGBA{0xFF, 0x7F, 0x50, 0xFF},
+	"cornflowerblue":       color.RGBA{0x64, 0x95, 0xED, 0xFF},
+	"cornsilk":             color.RGBA{0xFF, 0xF8, 0xDC, 0xFF},
+	"crimson":              color.RGBA{0xDC, 0x14, 0x3C, 0xFF},
+	"cyan":                 color.RGBA{0x00, 0xFF, 0xFF, 0xFF},
+	"darkblue":             color.RGBA{0x00, 0x00, 0x8B, 0xFF},
+	"darkcyan":             color.RGBA{0x00, 0x8B, 0x8B, 0xFF},
+	"darkgoldenrod":        color.RGBA{0xB8, 0x86, 0x0B, 0xFF},
+	"darkgray":             color.RGBA{0xA9, 0xA9, 0xA9, 0xFF},
+	"darkgreen":            color.RGBA{0x00, 0x64, 0x00, 0xFF},
+	"darkgrey":             color.RGBA{0xA9, 0xA9, 0xA9, 0xFF},
+	"darkkhaki":            color.RGBA{0xBD, 0xB7, 0x6B, 0xFF},
+	"darkmagenta":          color.RGBA{0x8B, 0x00, 0x8B, 0xFF},
+	"darkolivegreen":       color.RGBA{0x55, 0x6B, 0x2F, 0xFF},
+	"darkorange":           color.RGBA{0xFF, 0x8C, 0x00, 0xFF},
+	"darkorchid":           color.RGBA{0x99, 0x32, 0xCC, 0xFF},
+	"darkred":              color.RGBA{0x8B, 0x00, 0x00, 0xFF},
+	"darksalmon":           color.RGBA{0xE9, 0x96, 0x7A, 0xFF},
+	"darkseagreen":         color.RGBA{0x8F, 0xBC, 0x8F, 0xFF},
+	"darkslateblue":        color.RGBA{0x48, 0x3D, 0x8B, 0xFF},
+	"darkslategray":        color.RGBA{0x2F, 0x4F, 0x4F, 0xFF},
+	"darkslategrey":        color.RGBA{0x2F, 0x4F, 0x4F, 0xFF},
+	"darkturquoise":        color.RGBA{0x00, 0xCE, 0xD1, 0xFF},
+	"darkviolet":           color.RGBA{0x94, 0x00, 0xD3, 0xFF},
+	"deeppink":             color.RGBA{0xFF, 0x14, 0x93, 0xFF},
+	"deepskyblue":          color.RGBA{0x00, 0xBF, 0xFF, 0xFF},
+	"dimgray":              color.RGBA{0x69, 0x69, 0x69, 0xFF},
+	"dimgrey":              color.RGBA{0x69, 0x69, 0x69, 0xFF},
+	"dodgerblue":           color.RGBA{0x1E, 0x90, 0xFF, 0xFF},
+	"firebrick":            color.RGBA{0xB2, 0x22, 0x22, 0xFF},
+	"floralwhite":          color.RGBA{0xFF, 0xFA, 0xF0, 0xFF},
+	"forestgreen":          color.RGBA{0x22, 0x8B, 0x22, 0xFF},
+	"fuchsia":              color.RGBA{0xFF, 0x00, 0xFF, 0xFF},
+	"gainsboro":            color.RGBA{0xDC, 0xDC, 0xDC, 0xFF},
+	"ghostwhite":           color.RGBA{0xF8, 0xF8, 0xFF, 0xFF},
+	"gold":                 color.RGBA{0xFF, 0xD7, 0x00, 0xFF},
+	"goldenrod":            color.RGBA{0xDA, 0xA5, 0x20, 0xFF},
+	"gray":                 color.RGBA{0x80, 0x80, 0x80, 0xFF},
+	"green":                color.RGBA{0x00, 0xFF, 0x00, 0xFF},
+	"greenyellow":          color.RGBA{0xAD, 0xFF, 0x2F, 0xFF},
+	"grey":                 color.RGBA{0x80, 0x80, 0x80, 0xFF},
+	"honeydew":             color.RGBA{0xF0, 0xFF, 0xF0, 0xFF},
+	"hotpink":              color.RGBA{0xFF, 0x69, 0xB4, 0xFF},
+	"indianred":            color.RGBA{0xCD, 0x5C, 0x5C, 0xFF},
+	"indigo":               color.RGBA{0x4B, 0x00, 0x82, 0xFF},
+	"ivory":                color.RGBA{0xFF, 0xFF, 0xF0, 0xFF},
+	"khaki":                color.RGBA{0xF0, 0xE6, 0x8C, 0xFF},
+	"lavender":             color.RGBA{0xE6, 0xE6, 0xFA, 0xFF},
+	"lavenderblush":        color.RGBA{0xFF, 0xF0, 0xF5, 0xFF},
+	"lawngreen":            color.RGBA{0x7C, 0xFC, 0x00, 0xFF},
+	"lemonchiffon":         color.RGBA{0xFF, 0xFA, 0xCD, 0xFF},
+	"lightblue":            color.RGBA{0xAD, 0xD8, 0xE6, 0xFF},
+	"lightcoral":           color.RGBA{0xF0, 0x80, 0x80, 0xFF},
+	"lightcyan":            color.RGBA{0xE0, 0xFF, 0xFF, 0xFF},
+	"lightgoldenrodyellow": color.RGBA{0xFA, 0xFA, 0xD2, 0xFF},
+	"lightgray":            color.RGBA{0xD3, 0xD3, 0xD3, 0xFF},
+	"lightgreen":           color.RGBA{0x90, 0xEE, 0x90, 0xFF},
+	"lightgrey":            color.RGBA{0xD3, 0xD3, 0xD3, 0xFF},
+	"lightpink":            color.RGBA{0xFF, 0xB6, 0xC1, 0xFF},
+	"lightsalmon":          color.RGBA{0xFF, 0xA0, 0x7A, 0xFF},
+	"lightseagreen":        color.RGBA{0x20, 0xB2, 0xAA, 0xFF},
+	"lightskyblue":         color.RGBA{0x87, 0xCE, 0xFA, 0xFF},
+	"lightslategray":       color.RGBA{0x77, 0x88, 0x99, 0xFF},
+	"lightslategrey":       color.RGBA{0x77, 0x88, 0x99, 0xFF},
+	"lightsteelblue":       color.RGBA{0xB0, 0xC4, 0xDE, 0xFF},
+	"lightyellow":          color.RGBA{0xFF, 0xFF, 0xE0, 0xFF},
+	"lime":                 color.RGBA{0x00, 0xFF, 0x00, 0xFF},
+	"limegreen":            color.RGBA{0x32, 0xCD, 0x32, 0xFF},
+	"linen":                color.RGBA{0xFA, 0xF0, 0xE6, 0xFF},
+	"magenta":              color.RGBA{0xFF, 0x00, 0xFF, 0xFF},
+	"maroon":               color.RGBA{0x80, 0x00, 0x00, 0xFF},
+	"mediumaquamarine":     color.RGBA{0x66, 0xCD, 0xAA, 0xFF},
+	"mediumblue":           color.RGBA{0x00, 0x00, 0xCD, 0xFF},
+	"mediumorchid":         color.RGBA{0xBA, 0x55, 0xD3, 0xFF},
+	"mediumpurple":         color.RGBA{0x93, 0x70, 0xDB, 0xFF},
+	"mediumseagreen":       color.RGBA{0x3C, 0xB3, 0x71, 0xFF},
+	"mediumslateblue":      color.RGBA{0x7B, 0x68, 0xEE, 0xFF},
+	"mediumspringgreen":    color.RGBA{0x00, 0xFA, 0x9A, 0xFF},
+	"mediumturquoise":      color.RGBA{0x48, 0xD1, 0xCC, 0xFF},
+	"mediumvioletred":      color.RGBA{0xC7, 0x15, 0x85, 0xFF},
+	"midnightblue":         color.RGBA{0x19, 0x19, 0x70, 0xFF},
+	"mintcream":            color.RGBA{0xF5, 0xFF, 0xFA, 0xFF},
+	"mistyrose":            color.RGBA{0xFF, 0xE4, 0xE1, 0xFF},
+	"moccasin":             color.RGBA{0xFF, 0xE4, 0xB5, 0xFF},
+	"navajowhite":          color.RGBA{0xFF, 0xDE, 0xAD, 0xFF},
+	"navy":                 color.RGBA{0x00, 0x00, 0x80, 0xFF},
+	"oldlace":              color.RGBA{0xFD, 0xF5, 0xE6, 0xFF},
+	"olive":                color.RGBA{0x80, 0x80, 0x00, 0xFF},
+	"olivedrab":            color.RGBA{0x6B, 0x8E, 0x23, 0xFF},
+	"orange":               color.RGBA{0xFF, 0xA5, 0x00, 0xFF},
+	"orangered":            color.RGBA{0xFF, 0x45, 0x00, 0xFF},
+	"orchid":               color.RGBA{0xDA, 0x70, 0xD6, 0xFF},
+	"palegoldenrod":        color.RGBA{0xEE, 0xE8, 0xAA, 0xFF},
+	"palegreen":            color.RGBA{0x98, 0xFB, 0x98, 0xFF},
+	"paleturquoise":        color.RGBA{0xAF, 0xEE, 0xEE, 0xFF},
+	"palevioletred":        color.RGBA{0xDB, 0x70, 0x93, 0xFF},
+	"papayawhip":           color.RGBA{0xFF, 0xEF, 0xD5, 0xFF},
+	"peachpuff":            color.RGBA{0xFF, 0xDA, 0xB9, 0xFF},
+	"peru":                 color.RGBA{0xCD, 0x85, 0x3F, 0xFF},
+	"pink":                 color.RGBA{0xFF, 0xC0, 0xCB, 0xFF},
+	"plum":                 color.RGBA{0xDD, 0xA0, 0xDD, 0xFF},
+	"powderblue":           color.RGBA{0xB0, 0xE0, 0xE6, 0xFF},
+	"purple":               color.RGBA{0x80, 0x00, 0x80, 0xFF},
+	"rebeccapurple":        color.RGBA{0x66, 0x33, 0x99, 0xFF},
+	"red":                  color.RGBA{0xFF, 0x00, 0x00, 0xFF},
+	"rosybrown":            color.RGBA{0xBC, 0x8F, 0x8F, 0xFF},
+	"royalblue":            color.RGBA{0x41, 0x69, 0xE1, 0xFF},
+	"saddlebrown":          color.RGBA{0x8B, 0x45, 0x13, 0xFF},
+	"salmon":               color.RGBA{0xFA, 0x80, 0x72, 0xFF},
+	"sandybrown":           color.RGBA{0xF4, 0xA4, 0x60, 0xFF},
+	"seagreen":             color.RGBA{0x2E, 0x8B, 0x57, 0xFF},
+	"seashell":             color.RGBA{0xFF, 0xF5, 0xEE, 0xFF},
+	"sienna":               color.RGBA{0xA0, 0x52, 0x2D, 0xFF},
+	"silver":               color.RGBA{0xC0, 0xC0, 0xC0, 0xFF},
+	"skyblue":              color.RGBA{0x87, 0xCE, 0xEB, 0xFF},
+	"slateblue":            color.RGBA{0x6A, 0x5A, 0xCD, 0xFF},
+	"slategray":            color.RGBA{0x70, 0x80, 0x90, 0xFF},
+	"slategrey":            color.RGBA{0x70, 0x80, 0x90, 0xFF},
+	"snow":                 color.RGBA{0xFF, 0xFA, 0xFA, 0xFF},
+	"springgreen":          color.RGBA{0x00, 0xFF, 0x7F, 0xFF},
+	"steelblue":            color.RGBA{0x46, 0x82, 0xB4, 0xFF},
+	"tan":                  color.RGBA{0xD2, 0xB4, 0x8C, 0xFF},
+	"teal":                 color.RGBA{0x00, 0x80, 0x80, 0xFF},
+	"thistle":              color.RGBA{0xD8, 0xBF, 0xD8, 0xFF},
+	"tomato":               color.RGBA{0xFF, 0x63, 0x47, 0xFF},
+	"turquoise":            color.RGBA{0x40, 0xE0, 0xD0, 0xFF},
+	"violet":               color.RGBA{0xEE, 0x82, 0xEE, 0xFF},
+	"wheat":                color.RGBA{0xF5, 0xDE, 0xB3, 0xFF},
+	"white":                color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+	"whitesmoke":           color.RGBA{0xF5, 0xF5, 0xF5, 0xFF},
+	"yellow":               color.RGBA{0xFF, 0xFF, 0x00, 0xFF},
+	"yellowgreen":          color.RGBA{0x9A, 0xCD, 0x32, 0xFF}}
+
+type lifeRule struct {
+	birth    uint16
+	survival uint16
+}
+
+var gKnownRules = map[string]string{"life": "B3/S23",
+	"highlife":         "B36/S23",
+	"seeds":            "B2/S",
+	"daynight":         "B3678/S34678",
+	"lifewithoutdeath": "B3/S012345678",
+	"2x2":              "B36/S125",
+	"maze":             "B3/S12345",
+	"replicator":       "B1357/S1357",
+	"diamoeba":         "B35678/S5678",
+	"morley":           "B368/S245",
+	"anneal":           "B4678/S35678"}
+
+type boardTopology int
+
+const (
+	planeTopology boardTopology = iota
+	torusTopology
+	kleinXTopology
+	kleinYTopology
+	crossSurfaceTopology
+	sphereTopology
+)
+
+var gAvailTopologies = map[string]boardTopology{"plane": planeTopology,
+	"torus":         torusTopology,
+	"klein:x":       kleinXTopology,
+	"klein:y":       kleinYTopology,
+	"cross-surface": crossSurfaceTopology,
+	"sphere":        sphereTopology}
+
+type lifeUniverse interface {
+	setAlive(x, y int)
+	isAlive(x, y int) bool
+	nextGeneration()
+	nextGenerations(n int)
+	forEachAlive(area image.Rectangle, do func(x, y int))
+	getBoundingBox() image.Rectangle
+	clone() lifeUniverse
+}
+
+type boardUniverse struct {
+	cells     [][]byte
+	nextCells [][]byte
+	rule      lifeRule
+	topology  boardTopology
+	inverted  bool
+	workersNr int
+}
+
+// The board is surrounded by a halo of one cell, thus the cell (x, y) is the bit x + 1 of rows[y + 1].
+type bitBoardUniverse struct {
+	rows      [][]uint64
+	next      [][]uint64
+	rowMask   []uint64
+	xNr, yNr  int
+	rule      lifeRule
+	topology  boardTopology
+	inverted  bool
+	workersNr int
+}
+
+type cellCoord struct {
+	x, y int
+}
+
+type lifePattern struct {
+	name  string
+	rule  string
+	cells []cellCoord
+}
+
+type lifeStateKey struct {
+	hashA, hashB uint64
+	population   int
+}
+
+type lifeState struct {
+	generation int
+	origin     image.Point
+}
+
+// A cycle is found as long as its period is not longer than the states kept.
+type lifeHistory struct {
+	states map[lifeStateKey]lifeState
+	keys   []lifeStateKey
+	next   int
+	size   int
+}
+
+type lifeCycle struct {
+	first, period int
+	displacement  image.Point
+	population    int
+}
+
+type lifeStats struct {
+	Generation int     `json:"generation"`
+	Population int     `json:"population"`
+	Births     int     `json:"births"`
+	Deaths     int     `json:"deaths"`
+	BBoxX      int     `json:"bbox_x"`
+	BBoxY      int     `json:"bbox_y"`
+	BBoxWidth  int     `json:"bbox_width"`
+	BBoxHeight int     `json:"bbox_height"`
+	ChangeRate float64 `json:"change_rate"`
+}
+
+type censusObject struct {
+	Apgcode string `json:"apgcode"`
+	Name    string `json:"name,omitempty"`
+	Count   int    `json:"count"`
+}
+
+type lifeObject struct {
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Population int    `json:"population"`
+	Kind       string `json:"kind"`
+	Period     int    `json:"period,omitempty"`
+	Velocity   string `json:"velocity,omitempty"`
+	Apgcode    string `json:"apgcode"`
+	Name       string `json:"name,omitempty"`
+}
+
+type lifeCensus struct {
+	Rule          string         `json:"rule"`
+	Soup          string         `json:"soup"`
+	Soups         int            `json:"soups"`
+	UnstableSoups int            `json:"unstable_soups"`
+	Objects       []censusObject `json:"objects"`
+}
+
+type lifeStatsTracker struct {
+	previous map[cellCoord]struct{}
+	records  []lifeStats
+}
+
+type cellColorizer struct {
+	palette     []color.Color
+	tracksAge   bool
+	trailLength int
+	aliveIndex  func(age int) uint8
+	ghostIndex  func(deadFor int) uint8
+}
+
+type cellStyle struct {
+	shape         func(x, y, width, height int) bool
+	padding       int
+	grid          bool
+	gridIndex     uint8
+	gridThickness int
+}
+
+type frameCaption struct {
+	text       string
+	generation bool
+	population bool
+	corner     string
+	colorIndex uint8
+	scale      int
+}
+
+type gifSavings struct {
+	fullSize     int
+	size         int
+	mergedFrames int
+}
+
+type byteCounter int
+
+type gifStream struct {
+	out              *bufio.Writer
+	flusher          http.Flusher
+	err              error
+	litWidth         int
+	optimize         bool
+	reportSavings    bool
+	transparentIndex uint8
+	previous         *image.Paletted
+	pending          *image.Paletted
+	pendingDelay     int
+	mergedFrames     int
+	size, fullSize   byteCounter
+	fullLitWidth     int
+}
+
+// Stops the GIF as soon as the client is gone.
+type httpdClientWriter struct {
+	out     io.Writer
+	request *http.Request
+}
+
+type httpdErrorWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+type gifSubBlockWriter struct {
+	out   io.Writer
+	block [256]byte
+	size  int
+}
+
+type cellAgeTracker struct {
+	ages    map[cellCoord]int
+	deadFor map[cellCoord]int
+}
+
+type lifeCamera struct {
+	centerX, centerY float64
+	width, height    float64
+	scale            float64
+	frame            image.Rectangle
+	fit, follow      bool
+}
+
+type sparseUniverse struct {
+	cells map[cellCoord]struct{}
+	rule  lifeRule
+}
+
+// Nodes are canonical (the same contents are always the same pointer), thus they can be compared
+// by address and memoized.
+type hashLifeNode struct {
+	nw, ne, sw, se *hashLifeNode
+	level          uint
+	population     int
+	bounds         image.Rectangle
+}
+
+type hashLifeNodeKey struct {
+	nw, ne, sw, se *hashLifeNode
+}
+
+type hashLifeResultKey struct {
+	node *hashLifeNode
+	step uint
+}
+
+type hashLifeUniverse struct {
+	root             *hashLifeNode
+	originX, originY int
+	rule             lifeRule
+	deadCell         *hashLifeNode
+	aliveCell        *hashLifeNode
+	emptyNodes       []*hashLifeNode
+	nodes            map[hashLifeNodeKey]*hashLifeNode
+	results          map[hashLifeResultKey]*hashLifeNode
+}
+
+var gAvailEngines = map[string]func(xNr, yNr int, rule lifeRule, topology boardTopology,
+	workersNr int) (lifeUniverse, error){
+	"board":    makeBoardUniverse,
+	"bitboard": makeBitBoardUniverse,
+	"sparse":   makeSparseUniverse,
+	"hashlife": makeHashLifeUniverse}
+
+var gUnboundedEngines = map[string]bool{"sparse": true, "hashlife": true}
+
+var gAvailStatsFormats = map[string]func(out io.Writer, records []lifeStats) error{"csv": writeStatsAsCSV,
+	"json": writeStatsAsJSON}
+
+var gAvailChartFormats = map[string]func(out io.Writer, records []lifeStats, bkColor, fgColor color.Color) error{
+	".png": writePopulationChartAsPNG,
+	".svg": writePopulationChartAsSVG}
+
+var gAvailSymmetries = map[string][]func(cell cellCoord, width, height int) cellCoord{"C1": nil,
+	"C2": {rotateCell180},
+	"C4": {rotateCell90},
+	"D2": {mirrorCellX},
+	"D4": {mirrorCellX, mirrorCellY},
+	"D8": {rotateCell90, mirrorCellX}}
+
+var gAvailCensusFormats = map[string]func(out io.Writer, census lifeCensus) error{"table": writeCensusAsTable,
+	"json": writeCensusAsJSON}
+
+var gAvailIdentifyFormats = map[string]func(out io.Writer, objects []lifeObject) error{"table": writeObjectsAsTable,
+	"json": writeObjectsAsJSON}
+
+var gPatternLibrary = map[string]string{
+	"acorn":             "#N Acorn\nx = 7, y = 3, rule = B3/S23\nbo$3bo$2o2b3o!",
+	"beacon":            "#N Beacon\nx = 4, y = 4, rule = B3/S23\n2o$2o$2b2o$2b2o!",
+	"beehive":           "#N Beehive\nx = 4, y = 3, rule = B3/S23\nb2o$o2bo$b2o!",
+	"blinker":           "#N Blinker\nx = 3, y = 1, rule = B3/S23\n3o!",
+	"block":             "#N Block\nx = 2, y = 2, rule = B3/S23\n2o$2o!",
+	"boat":              "#N Boat\nx = 3, y = 3, rule = B3/S23\n2o$obo$bo!",
+	"diehard":           "#N Diehard\nx = 8, y = 3, rule = B3/S23\n6bo$2o$bo3b3o!",
+	"eater":             "#N Eater\nx = 4, y = 4, rule = B3/S23\n2o$obo$2bo$2b2o!",
+	"glider":            "#N Glider\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!",
+	"gosper-glider-gun": "#N Gosper glider gun\nx = 36, y = 9, rule = B3/S23\n24bo$22bobo$12b2o6b2o12b2o$11bo3bo4b2o12b2o$2o8bo5bo3b2o$2o8bo3bob2o4bobo$10bo5bo7bo$11bo3bo$12b2o!",
+	"hwss":              "#N Heavyweight spaceship\nx = 7, y = 5, rule = B3/S23\n3b2o$bo4bo$o$o5bo$6o!",
+	"loaf":              "#N Loaf\nx = 4, y = 4, rule = B3/S23\nb2o$o2bo$bobo$2bo!",
+	"lwss":              "#N Lightweight spaceship\nx = 5, y = 4, rule = B3/S23\nbo2bo$o$o3bo$4o!",
+	"mwss":              "#N Middleweight spaceship\nx = 6, y = 5, rule = B3/S23\n3bo$bo3bo$o$o4bo$5o!",
+	"pentadecathlon":    "#N Pentadecathlon\nx = 10, y = 3, rule = B3/S23\n2bo4bo$2ob4ob2o$2bo4bo!",
+	"pi-heptomino":      "#N Pi-heptomino\nx = 3, y = 3, rule = B3/S23\n3o$obo$obo!",
+	"pulsar":            "#N Pulsar\nx = 13, y = 13, rule = B3/S23\n2b3o3b3o2$o4bobo4bo$o4bobo4bo$o4bobo4bo$2b3o3b3o2$2b3o3b3o$o4bobo4bo$o4bobo4bo$o4bobo4bo2$2b3o3b3o!",
+	"r-pentomino":       "#N R-pentomino\nx = 3, y = 3, rule = B3/S23\nb2o$2o$bo!",
+	"ship":              "#N Ship\nx = 3, y = 3, rule = B3/S23\n2o$obo$b2o!",
+	"simkin-glider-gun": "#N Simkin glider gun\nx = 33, y = 21, rule = B3/S23\n2o5b2o$2o5b2o2$4b2o$4b2o5$22b2ob2o$21bo5bo$21bo6bo2b2o$21b3o3bo3b2o$26bo4$20b2o$20bo$21b3o$23bo!",
+	"toad":              "#N Toad\nx = 4, y = 2, rule = B3/S23\nb3o$3o!",
+	"tub":               "#N Tub\nx = 3, y = 3, rule = B3/S23\nbo$obo$bo!"}
+
+var gKnownObjectNames = map[string]string{"xs4_33": "block",
+	"xs6_696":      "beehive",
+	"xs7_2596":     "loaf",
+	"xs5_253":      "boat",
+	"xs6_356":      "ship",
+	"xs4_252":      "tub",
+	"xs8_6996":     "pond",
+	"xs7_25ac":     "long boat",
+	"xs6_25a4":     "barge",
+	"xs8_69ic":     "mango",
+	"xs6_bd":       "snake",
+	"xs6_39c":      "aircraft carrier",
+	"xs9_4aar":     "hat",
+	"xs9_31ego":    "integral sign",
+	"xs7_178c":     "eater",
+	"xp2_7":        "blinker",
+	"xp2_7e":       "toad",
+	"xp2_318c":     "beacon",
+	"xp15_4r4z4r4": "pentadecathlon",
+	"xp3_co9nas0san9oczgoldlo0oldlogz1047210127401": "pulsar",
+	"xq4_153":     "glider",
+	"xq4_6frc":    "lightweight spaceship",
+	"xq4_27dee6":  "middleweight spaceship",
+	"xq4_27deee6": "heavyweight spaceship"}
+
+var gAvailColorModes = map[string]func(bkColor, fgColor, agedColor color.Color, stops []color.Color,
+	trailLength int) *cellColorizer{
+	"binary": makeBinaryColorizer,
+	"age":    makeAgeColorizer,
+	"trail":  makeTrailColorizer,
+	"heat":   makeHeatColorizer}
+
+var gBitmapFont = [...][5]byte{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5F, 0x00, 0x00}, // '!'
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // '"'
+	{0x14, 0x7F, 0x14, 0x7F, 0x14}, // '#'
+	{0x24, 0x2A, 0x7F, 0x2A, 0x12}, // '$'
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // '%'
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // '&'
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '\''
+	{0x00, 0x1C, 0x22, 0x41, 0x00}, // '('
+	{0x00, 0x41, 0x22, 0x1C, 0x00}, // ')'
+	{0x08, 0x2A, 0x1C, 0x2A, 0x08}, // '*'
+	{0x08, 0x08, 0x3E, 0x08, 0x08}, // '+'
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ','
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // '-'
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // '.'
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // '/'
+	{0x3E, 0x51, 0x49, 0x45, 0x3E}, // '0'
+	{0x00, 0x42, 0x7F, 0x40, 0x00}, // '1'
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // '2'
+	{0x21, 0x41, 0x45, 0x4B, 0x31}, // '3'
+	{0x18, 0x14, 0x12, 0x7F, 0x10}, // '4'
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // '5'
+	{0x3C, 0x4A, 0x49, 0x49, 0x30}, // '6'
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // '7'
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // '8'
+	{0x06, 0x49, 0x49, 0x29, 0x1E}, // '9'
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // ':'
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ';'
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // '<'
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // '='
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // '>'
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // '?'
+	{0x32, 0x49, 0x79, 0x41, 0x3E}, // '@'
+	{0x7E, 0x11, 0x11, 0x11, 0x7E}, // 'A'
+	{0x7F, 0x49, 0x49, 0x49, 0x36}, // 'B'
+	{0x3E, 0x41, 0x41, 0x41, 0x22}, // 'C'
+	{0x7F, 0x41, 0x41, 0x22, 0x1C}, // 'D'
+	{0x7F, 0x49, 0x49, 0x49, 0x41}, // 'E'
+	{0x7F, 0x09, 0x09, 0x09, 0x01}, // 'F'
+	{0x3E, 0x41, 0x49, 0x49, 0x7A}, // 'G'
+	{0x7F, 0x08, 0x08, 0x08, 0x7F}, // 'H'
+	{0x00, 0x41, 0x7F, 0x41, 0x00}, // 'I'
+	{0x20, 0x40, 0x41, 0x3F, 0x01}, // 'J'
+	{0x7F, 0x08, 0x14, 0x22, 0x41}, // 'K'
+	{0x7F, 0x40, 0x40, 0x40, 0x40}, // 'L'
+	{0x7F, 0x02, 0x0C, 0x02, 0x7F}, // 'M'
+	{0x7F, 0x04, 0x08, 0x10, 0x7F}, // 'N'
+	{0x3E, 0x41, 0x41, 0x41, 0x3E}, // 'O'
+	{0x7F, 0x09, 0x09, 0x09, 0x06}, // 'P'
+	{0x3E, 0x41, 0x51, 0x21, 0x5E}, // 'Q'
+	{0x7F, 0x09, 0x19, 0x29, 0x46}, // 'R'
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // 'S'
+	{0x01, 0x01, 0x7F, 0x01, 0x01}, // 'T'
+	{0x3F, 0x40, 0x40, 0x40, 0x3F}, // 'U'
+	{0x1F, 0x20, 0x40, 0x20, 0x1F}, // 'V'
+	{0x3F, 0x40, 0x38, 0x40, 0x3F}, // 'W'
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // 'X'
+	{0x07, 0x08, 0x70, 0x08, 0x07}, // 'Y'
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // 'Z'
+	{0x00, 0x7F, 0x41, 0x41, 0x00}, // '['
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // '\\'
+	{0x00, 0x41, 0x41, 0x7F, 0x00}, // ']'
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // '^'
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // '_'
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // '`'
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // 'a'
+	{0x7F, 0x48, 0x44, 0x44, 0x38}, // 'b'
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // 'c'
+	{0x38, 0x44, 0x44, 0x48, 0x7F}, // 'd'
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // 'e'
+	{0x08, 0x7E, 0x09, 0x01, 0x02}, // 'f'
+	{0x0C, 0x52, 0x52, 0x52, 0x3E}, // 'g'
+	{0x7F, 0x08, 0x04, 0x04, 0x78}, // 'h'
+	{0x00, 0x44, 0x7D, 0x40, 0x00}, // 'i'
+	{0x20, 0x40, 0x44, 0x3D, 0x00}, // 'j'
+	{0x7F, 0x10, 0x28, 0x44, 0x00}, // 'k'
+	{0x00, 0x41, 0x7F, 0x40, 0x00}, // 'l'
+	{0x7C, 0x04, 0x18, 0x04, 0x78}, // 'm'
+	{0x7C, 0x08, 0x04, 0x04, 0x78}, // 'n'
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // 'o'
+	{0x7C, 0x14, 0x14, 0x14, 0x08}, // 'p'
+	{0x08, 0x14, 0x14, 0x18, 0x7C}, // 'q'
+	{0x7C, 0x08, 0x04, 0x04, 0x08}, // 'r'
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // 's'
+	{0x04, 0x3F, 0x44, 0x40, 0x20}, // 't'
+	{0x3C, 0x40, 0x40, 0x20, 0x7C}, // 'u'
+	{0x1C, 0x20, 0x40, 0x20, 0x1C}, // 'v'
+	{0x3C, 0x40, 0x30, 0x40, 0x3C}, // 'w'
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // 'x'
+	{0x0C, 0x50, 0x50, 0x50, 0x3C}, // 'y'
+	{0x44, 0x64, 0x54, 0x4C, 0x44}, // 'z'
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // '{'
+	{0x00, 0x00, 0x7F, 0x00, 0x00}, // '|'
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // '}'
+	{0x10, 0x08, 0x18, 0x10, 0x08}, // '~'
+}
+
+var gAvailTextCorners = map[string]func(area image.Rectangle, size image.Point) image.Point{
+	"top-left": func(area image.Rectangle, size image.Point) image.Point { return area.Min },
+	"top-right": func(area image.Rectangle, size image.Point) image.Point {
+		return image.Pt(area.Max.X-size.X, area.Min.Y)
+	},
+	"bottom-left": func(area image.Rectangle, size image.Point) image.Point {
+		return image.Pt(area.Min.X, area.Max.Y-size.Y)
+	},
+	"bottom-right": func(area image.Rectangle, size image.Point) image.Point { return area.Max.Sub(size) }}
+
+var gAvailCellShapes = map[string]func(x, y, width, height int) bool{
+	"square":  isInSquare,
+	"circle":  isInCircle,
+	"rounded": isInRoundedSquare,
+	"diamond": isInDiamond}
+
+var gAvailExportFormats = map[string]func(pattern lifePattern) string{"rle": makeRLEPatternData,
+	"cells": makePlainTextPatternData}
 
 var gAvailCommands = map[string]func() int{"gif": dumpGIF,
-	"httpd": httpdGIFdumper,
-	"help":  help,
+	"export":   exportPattern,
+	"stats":    dumpStats,
+	"census":   takeCensus,
+	"identify": identifyObjects,
+	"patterns": listPatterns,
+	"httpd":    httpdGIFdumper,
+	"help":     help,
 	"version": func() int {
 		fmt.Fprintf(os.Stdout, "googol-%s\n", googolVersion)
 		return 0
 	}}
 
 var gAvailCommandHelpers = map[string]func() int{"gif": helpGIF,
-	"httpd": helpHttpd,
+	"export":   helpExport,
+	"stats":    helpStats,
+	"census":   helpCensus,
+	"identify": helpIdentify,
+	"patterns": helpPatterns,
+	"httpd":    helpHttpd,
 	"version": func() int {
 		fmt.Fprintf(os.Stdout, "usage: googol version\n")
 		return 0
@@ -161,31 +869,70 @@ var setCheckboxState = func(data interface{}) string {
 	return state
 }
 
-var getColorOption = func(data interface{}) (template.HTML, color.Color) {
-	var selColor string
+var getColorOption = func(colorName *string, random *rand.Rand) color.Color {
+	cl := getColor(*colorName, random)
+	*colorName = strings.ToLower(getHexColor(cl))
+	return cl
+}
+
+var getSelectOption = func(data interface{}, optionList []string) (template.HTML, string) {
+	var selOption string
 	switch data.(type) {
 	case string:
-		selColor = data.(string)
+		selOption = data.(string)
 	case []string:
-		selColor = data.([]string)[0]
+		selOption = data.([]string)[0]
 	}
-	colors := reflect.ValueOf(gAvailColors).MapKeys()
-	colorsLen := len(colors) + 1
-	colorList := make([]string, colorsLen)
-	for c := 0; c < colorsLen - 1; c++ {
-		colorList[c] = colors[c].String()
-	}
-        colorList[colorsLen - 1] = "random"
-	sort.Strings(colorList)
+	sort.Strings(optionList)
 	var strData string
-	for _, c := range colorList {
-		if c != selColor {
-			strData += "<option value=\"" + c + "\">" + c + "</option>\n"
+	for _, o := range optionList {
+		if o != selOption {
+			strData += "<option value=\"" + o + "\">" + o + "</option>\n"
 		} else {
-			strData += "<option value=\"" + c + "\" selected>" + c + "</option>\n"
+			strData += "<option value=\"" + o + "\" selected>" + o + "</option>\n"
 		}
 	}
-	return template.HTML(strData), getColor(selColor)
+	return template.HTML(strData), selOption
+}
+
+var getTopologyOption = func(data interface{}) (template.HTML, string) {
+	topologyList := make([]string, 0, len(gAvailTopologies))
+	for t := range gAvailTopologies {
+		topologyList = append(topologyList, t)
+	}
+	return getSelectOption(data, topologyList)
+}
+
+var getEngineOption = func(data interface{}) (template.HTML, string) {
+	engineList := make([]string, 0, len(gAvailEngines))
+	for e := range gAvailEngines {
+		engineList = append(engineList, e)
+	}
+	return getSelectOption(data, engineList)
+}
+
+var getColorModeOption = func(data interface{}) (template.HTML, string) {
+	colorModeList := make([]string, 0, len(gAvailColorModes))
+	for c := range gAvailColorModes {
+		colorModeList = append(colorModeList, c)
+	}
+	return getSelectOption(data, colorModeList)
+}
+
+var getCellShapeOption = func(data interface{}) (template.HTML, string) {
+	cellShapeList := make([]string, 0, len(gAvailCellShapes))
+	for s := range gAvailCellShapes {
+		cellShapeList = append(cellShapeList, s)
+	}
+	return getSelectOption(data, cellShapeList)
+}
+
+var getTextCornerOption = func(data interface{}) (template.HTML, string) {
+	textCornerList := make([]string, 0, len(gAvailTextCorners))
+	for c := range gAvailTextCorners {
+		textCornerList = append(textCornerList, c)
+	}
+	return getSelectOption(data, textCornerList)
 }
 
 var getInitialState = func(data interface{}) []string {
@@ -202,8 +949,9 @@ var getInitialState = func(data interface{}) []string {
 		}
 	}
 	for _, s := range dataList {
-		matches, _ := regexp.MatchString(`--[0-9]+,[0-9]+\.`, s)
-		if matches {
+		isCell, _ := regexp.MatchString(`---?[0-9]+,-?[0-9]+\.`, s)
+		isPlacement, _ := regexp.MatchString(`^[a-z][a-z0-9-]*(@-?[0-9]+,-?[0-9]+)?(:[a-z0-9-]+)*$`, s)
+		if isCell || isPlacement {
 			state = append(state, s)
 		}
 	}
@@ -211,20 +959,58 @@ var getInitialState = func(data interface{}) []string {
 }
 
 var gFieldsFiller = map[string]func(*GoogolRequest, interface{}){
-	"Addr":         nil,
-	"Port":         nil,
-	"Proto":        nil,
-	"InitialState": func(req *GoogolRequest, data interface{}) { req.InitialState = getInitialState(data) },
-	"BoardWidth":   func(req *GoogolRequest, data interface{}) { setField(&req.BoardWidth, data) },
-	"BoardHeight":  func(req *GoogolRequest, data interface{}) { setField(&req.BoardHeight, data) },
-	"GIFWidth":     func(req *GoogolRequest, data interface{}) { setField(&req.GIFWidth, data) },
-	"GIFHeight":    func(req *GoogolRequest, data interface{}) { setField(&req.GIFHeight, data) },
-	"Delay":        func(req *GoogolRequest, data interface{}) { setField(&req.Delay, data) },
-	"CellSizeInPx": func(req *GoogolRequest, data interface{}) { setField(&req.CellSizeInPx, data) },
-	"GenTotal":     func(req *GoogolRequest, data interface{}) { setField(&req.GenTotal, data) },
-	"BkColor":      func(req *GoogolRequest, data interface{}) { req.BkColor, req.SelectedBkColor = getColorOption(data) },
-	"FgColor":      func(req *GoogolRequest, data interface{}) { req.FgColor, req.SelectedFgColor = getColorOption(data) },
-	"Endless":      func(req *GoogolRequest, data interface{}) { req.Endless = setCheckboxState(data) }}
+	"Addr":            nil,
+	"Port":            nil,
+	"Proto":           nil,
+	"InitialState":    func(req *GoogolRequest, data interface{}) { req.InitialState = getInitialState(data) },
+	"Pattern":         func(req *GoogolRequest, data interface{}) { setField(&req.Pattern, data) },
+	"PatternAt":       func(req *GoogolRequest, data interface{}) { setField(&req.PatternAt, data) },
+	"BoardWidth":      func(req *GoogolRequest, data interface{}) { setField(&req.BoardWidth, data) },
+	"BoardHeight":     func(req *GoogolRequest, data interface{}) { setField(&req.BoardHeight, data) },
+	"GIFWidth":        func(req *GoogolRequest, data interface{}) { setField(&req.GIFWidth, data) },
+	"GIFHeight":       func(req *GoogolRequest, data interface{}) { setField(&req.GIFHeight, data) },
+	"Delay":           func(req *GoogolRequest, data interface{}) { setField(&req.Delay, data) },
+	"CellSizeInPx":    func(req *GoogolRequest, data interface{}) { setField(&req.CellSizeInPx, data) },
+	"GenTotal":        func(req *GoogolRequest, data interface{}) { setField(&req.GenTotal, data) },
+	"GenStep":         func(req *GoogolRequest, data interface{}) { setField(&req.GenStep, data) },
+	"PopulationStrip": func(req *GoogolRequest, data interface{}) { setField(&req.PopulationStrip, data) },
+	"Rule":            func(req *GoogolRequest, data interface{}) { setField(&req.Rule, data) },
+	"Topology": func(req *GoogolRequest, data interface{}) {
+		req.Topology, req.SelectedTopology = getTopologyOption(data)
+	},
+	"Engine":      func(req *GoogolRequest, data interface{}) { req.Engine, req.SelectedEngine = getEngineOption(data) },
+	"Viewport":    func(req *GoogolRequest, data interface{}) { setField(&req.Viewport, data) },
+	"Follow":      func(req *GoogolRequest, data interface{}) { req.Follow = setCheckboxState(data) },
+	"Fit":         func(req *GoogolRequest, data interface{}) { req.Fit = setCheckboxState(data) },
+	"StopOnCycle": func(req *GoogolRequest, data interface{}) { req.StopOnCycle = setCheckboxState(data) },
+	"Identify":    func(req *GoogolRequest, data interface{}) { req.Identify = setCheckboxState(data) },
+	"BkColor":     func(req *GoogolRequest, data interface{}) { setField(&req.BkColor, data) },
+	"FgColor":     func(req *GoogolRequest, data interface{}) { setField(&req.FgColor, data) },
+	"Seed":        func(req *GoogolRequest, data interface{}) { setField(&req.Seed, data) },
+	"Soup":        func(req *GoogolRequest, data interface{}) { setField(&req.Soup, data) },
+	"Use":         func(req *GoogolRequest, data interface{}) { setField(&req.Use, data) },
+	"ColorMode": func(req *GoogolRequest, data interface{}) {
+		req.ColorMode, req.SelectedColorMode = getColorModeOption(data)
+	},
+	"AgedColor":     func(req *GoogolRequest, data interface{}) { setField(&req.AgedColor, data) },
+	"TrailLength":   func(req *GoogolRequest, data interface{}) { setField(&req.TrailLength, data) },
+	"Grid":          func(req *GoogolRequest, data interface{}) { req.Grid = setCheckboxState(data) },
+	"GridColor":     func(req *GoogolRequest, data interface{}) { setField(&req.GridColor, data) },
+	"GridThickness": func(req *GoogolRequest, data interface{}) { setField(&req.GridThickness, data) },
+	"CellShape": func(req *GoogolRequest, data interface{}) {
+		req.CellShape, req.SelectedCellShape = getCellShapeOption(data)
+	},
+	"CellPadding":    func(req *GoogolRequest, data interface{}) { setField(&req.CellPadding, data) },
+	"Caption":        func(req *GoogolRequest, data interface{}) { setField(&req.Caption, data) },
+	"ShowGeneration": func(req *GoogolRequest, data interface{}) { req.ShowGeneration = setCheckboxState(data) },
+	"ShowPopulation": func(req *GoogolRequest, data interface{}) { req.ShowPopulation = setCheckboxState(data) },
+	"TextCorner": func(req *GoogolRequest, data interface{}) {
+		req.TextCorner, req.SelectedTextCorner = getTextCornerOption(data)
+	},
+	"TextColor": func(req *GoogolRequest, data interface{}) { setField(&req.TextColor, data) },
+	"TextScale": func(req *GoogolRequest, data interface{}) { setField(&req.TextScale, data) },
+	"Endless":   func(req *GoogolRequest, data interface{}) { req.Endless = setCheckboxState(data) },
+	"Optimize":  func(req *GoogolRequest, data interface{}) { req.Optimize = setCheckboxState(data) }}
 
 var gDefaultFields = map[string]func(*GoogolRequest){
 	"Addr": func(req *GoogolRequest) { req.Addr = getOption("addr", "localhost") },
@@ -245,6 +1031,8 @@ var gDefaultFields = map[string]func(*GoogolRequest){
 		}
 	},
 	"InitialState": func(req *GoogolRequest) { req.InitialState = getInitialState(os.Args[2:]) },
+	"Pattern":      func(req *GoogolRequest) { req.Pattern = gDefaultPatternData },
+	"PatternAt":    func(req *GoogolRequest) { req.PatternAt = getOption("pattern-at", gDefaultPatternAt) },
 	"BoardWidth":   func(req *GoogolRequest) { req.BoardWidth = getOption("board-width", gDefaultBoardWidth) },
 	"BoardHeight":  func(req *GoogolRequest) { req.BoardHeight = getOption("board-height", gDefaultBoardHeight) },
 	"GIFWidth":     func(req *GoogolRequest) { req.GIFWidth = getOption("gif-width", gDefaultBoardWidth) },
@@ -252,18 +1040,78 @@ var gDefaultFields = map[string]func(*GoogolRequest){
 	"Delay":        func(req *GoogolRequest) { req.Delay = getOption("delay", gDefaultDelay) },
 	"CellSizeInPx": func(req *GoogolRequest) { req.CellSizeInPx = getOption("cell-size-in-px", "1") },
 	"GenTotal":     func(req *GoogolRequest) { req.GenTotal = getOption("gen-total", gDefaultGenTotal) },
-	"BkColor": func(req *GoogolRequest) {
-		req.BkColor, req.SelectedBkColor = getColorOption(getOption("bk-color", gDefaultBkColor))
+	"GenStep":      func(req *GoogolRequest) { req.GenStep = getOption("gen-step", gDefaultGenStep) },
+	"PopulationStrip": func(req *GoogolRequest) {
+		req.PopulationStrip = getOption("population-strip", gDefaultPopulationStrip)
+	},
+	"Rule": func(req *GoogolRequest) { req.Rule = getOption("rule", gDefaultRule) },
+	"Topology": func(req *GoogolRequest) {
+		req.Topology, req.SelectedTopology = getTopologyOption(getOption("topology", gDefaultTopology))
+	},
+	"Engine": func(req *GoogolRequest) {
+		req.Engine, req.SelectedEngine = getEngineOption(getOption("engine", gDefaultEngine))
+	},
+	"Viewport": func(req *GoogolRequest) { req.Viewport = getOption("viewport", gDefaultViewport) },
+	"Follow":   func(req *GoogolRequest) { req.Follow = setCheckboxState(getBoolOption("follow", gDefaultFollow)) },
+	"Fit":      func(req *GoogolRequest) { req.Fit = setCheckboxState(getBoolOption("fit", gDefaultFit)) },
+	"StopOnCycle": func(req *GoogolRequest) {
+		req.StopOnCycle = setCheckboxState(getBoolOption("stop-on-cycle", gDefaultStopOnCycle))
+	},
+	"Identify": func(req *GoogolRequest) {
+		req.Identify = setCheckboxState(getBoolOption("identify", gDefaultIdentify))
 	},
-	"FgColor": func(req *GoogolRequest) {
-		req.FgColor, req.SelectedFgColor = getColorOption(getOption("fg-color", gDefaultFgColor))
+	"BkColor": func(req *GoogolRequest) { req.BkColor = getOption("bk-color", gDefaultBkColor) },
+	"FgColor": func(req *GoogolRequest) { req.FgColor = getOption("fg-color", gDefaultFgColor) },
+	"Seed":    func(req *GoogolRequest) { req.Seed = getOption("seed", "") },
+	"Soup":    func(req *GoogolRequest) { req.Soup = getOption("soup", "") },
+	"Use":     func(req *GoogolRequest) { req.Use = strings.Join(getOptions("use"), " ") },
+	"ColorMode": func(req *GoogolRequest) {
+		req.ColorMode, req.SelectedColorMode = getColorModeOption(getOption("color-mode", gDefaultColorMode))
 	},
-	"Endless": func(req *GoogolRequest) { req.Endless = setCheckboxState(getBoolOption("endless", gDefaultEndless)) }}
+	"AgedColor":   func(req *GoogolRequest) { req.AgedColor = getOption("aged-color", gDefaultAgedColor) },
+	"TrailLength": func(req *GoogolRequest) { req.TrailLength = getOption("trail-length", gDefaultTrailLength) },
+	"Grid":        func(req *GoogolRequest) { req.Grid = setCheckboxState(len(getOption("grid", "")) > 0) },
+	"GridColor":   func(req *GoogolRequest) { req.GridColor = getOption("grid", gDefaultGridColor) },
+	"GridThickness": func(req *GoogolRequest) {
+		req.GridThickness = getOption("grid-thickness", gDefaultGridThickness)
+	},
+	"CellShape": func(req *GoogolRequest) {
+		req.CellShape, req.SelectedCellShape = getCellShapeOption(getOption("cell-shape", gDefaultCellShape))
+	},
+	"CellPadding": func(req *GoogolRequest) { req.CellPadding = getOption("cell-padding", gDefaultCellPadding) },
+	"Caption":     func(req *GoogolRequest) { req.Caption = getOption("caption", "") },
+	"ShowGeneration": func(req *GoogolRequest) {
+		req.ShowGeneration = setCheckboxState(getBoolOption("show-generation", gDefaultShowGeneration))
+	},
+	"ShowPopulation": func(req *GoogolRequest) {
+		req.ShowPopulation = setCheckboxState(getBoolOption("show-population", gDefaultShowPopulation))
+	},
+	"TextCorner": func(req *GoogolRequest) {
+		req.TextCorner, req.SelectedTextCorner = getTextCornerOption(getOption("text-corner", gDefaultTextCorner))
+	},
+	"TextColor": func(req *GoogolRequest) {
+		req.TextColor = getOption("text-color", getOption("fg-color", gDefaultFgColor))
+	},
+	"TextScale": func(req *GoogolRequest) { req.TextScale = getOption("text-scale", gDefaultTextScale) },
+	"Endless":   func(req *GoogolRequest) { req.Endless = setCheckboxState(getBoolOption("endless", gDefaultEndless)) },
+	"Optimize": func(req *GoogolRequest) {
+		req.Optimize = setCheckboxState(getBoolOption("optimize", gDefaultOptimize))
+	}}
 
 var gMaxBoardWidth int = 500
 
 var gMaxBoardHeight int = 500
 
+var gMaxGenTotal int = 10000
+
+var gWorkersNr int = runtime.GOMAXPROCS(0)
+
+var gIdentifyGap int = 1
+
+var gDefaultPatternData string
+
+var gGIFErrorTemplate string = "{{.Error}}\n"
+
 var gFormTemplate string = `
 <html>
     <title>Googol webserver</title>
@@ -280,6 +1128,14 @@ var gFormTemplate string = `
                             <td><b>Initial state</b>:</td>
                             <td><input type="text" name="InitialState" style="text-align:right;width:430px" value="{{range .InitialState}}{{.}} {{end}}"></td>
                         </tr>
+                        <tr>
+                            <td><b>Pattern</b>:</td>
+                            <td><textarea name="Pattern" style="width:430px" rows=5>{{.Pattern}}</textarea></td>
+                        </tr>
+                        <tr>
+                            <td><b>Pattern at</b>:</td>
+                            <td><input type="text" name="PatternAt" style="text-align:right;width:430px" value="{{.PatternAt}}"></td>
+                        </tr>
                         <tr>
                             <td><b>Board width</b>:</td>
                             <td><input type="number" name="BoardWidth" style="text-align:right;width:430px" value="{{.BoardWidth}}"></td>
@@ -309,21 +1165,159 @@ var gFormTemplate string = `
                             <td><input type="number" name="GenTotal" style="text-align:right;width:430px" size=50 value="{{.GenTotal}}"></td>
                         </tr>
                         <tr>
-                            <td><b>Background color</b>:</td>
+                            <td><b>Generations per frame</b>:</td>
+                            <td><input type="number" name="GenStep" style="text-align:right;width:430px" size=50 value="{{.GenStep}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Population strip height</b>:</td>
+                            <td><input type="number" name="PopulationStrip" style="text-align:right;width:430px" size=50 value="{{.PopulationStrip}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Seed</b>:</td>
+                            <td><input type="text" name="Seed" style="text-align:right;width:430px" value="{{.Seed}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Soup</b>:</td>
+                            <td><input type="text" name="Soup" style="text-align:right;width:430px" value="{{.Soup}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Library patterns</b>:</td>
+                            <td><input type="text" name="Use" style="text-align:right;width:430px" value="{{.Use}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Rule</b>:</td>
+                            <td><input type="text" name="Rule" style="text-align:right;width:430px" value="{{.Rule}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Topology</b>:</td>
+                            <td>
+                                <select name="Topology" style="width:430px;text-align:right">
+                                    {{.Topology}}
+                                </select>
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Engine</b>:</td>
                             <td>
-                                <select name="BkColor" style="width:430px;text-align:right">
-                                    {{.BkColor}}
+                                <select name="Engine" style="width:430px;text-align:right">
+                                    {{.Engine}}
                                 </select>
                             </td>
                         </tr>
+                        <tr>
+                            <td><b>Viewport</b>:</td>
+                            <td><input type="text" name="Viewport" style="text-align:right;width:430px" value="{{.Viewport}}"></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="Follow" value="1" {{.Follow}}>
+                            <b>Follow the pattern</b></td>
+                            <td></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="Fit" value="1" {{.Fit}}>
+                            <b>Fit the viewport to the GIF</b></td>
+                            <td></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="StopOnCycle" value="1" {{.StopOnCycle}}>
+                            <b>Stop on cycle</b></td>
+                            <td></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="Identify" value="1" {{.Identify}}>
+                            <b>Identify objects</b></td>
+                            <td></td>
+                        </tr>
+                        <tr>
+                            <td><b>Background color</b>:</td>
+                            <td>
+                                <input type="color" name="BkColor" style="width:430px" value="{{.BkColor}}">
+                            </td>
+                        </tr>
                         <tr>
                             <td><b>Foreground color</b>:</td>
                             <td>
-                                <select name="FgColor" style="width:430px;text-align:right">
-                                    {{.FgColor}}
+                                <input type="color" name="FgColor" style="width:430px" value="{{.FgColor}}">
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Color mode</b>:</td>
+                            <td>
+                                <select name="ColorMode" style="width:430px;text-align:right">
+                                    {{.ColorMode}}
+                                </select>
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Aged color</b>:</td>
+                            <td>
+                                <input type="color" name="AgedColor" style="width:430px" value="{{.AgedColor}}">
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Trail length</b>:</td>
+                            <td><input type="number" name="TrailLength" style="text-align:right;width:430px" value="{{.TrailLength}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Cell shape</b>:</td>
+                            <td>
+                                <select name="CellShape" style="width:430px;text-align:right">
+                                    {{.CellShape}}
+                                </select>
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Cell padding</b>:</td>
+                            <td><input type="number" name="CellPadding" style="text-align:right;width:430px" value="{{.CellPadding}}"></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="Grid" value="1" {{.Grid}}>
+                            <b>Grid</b></td>
+                            <td>
+                                <input type="color" name="GridColor" style="width:430px" value="{{.GridColor}}">
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Grid thickness</b>:</td>
+                            <td><input type="number" name="GridThickness" style="text-align:right;width:430px" value="{{.GridThickness}}"></td>
+                        </tr>
+                        <tr>
+                            <td><b>Caption</b>:</td>
+                            <td><input type="text" name="Caption" style="text-align:right;width:430px" value="{{.Caption}}"></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="ShowGeneration" value="1" {{.ShowGeneration}}>
+                            <b>Show generation</b></td>
+                            <td></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="ShowPopulation" value="1" {{.ShowPopulation}}>
+                            <b>Show population</b></td>
+                            <td></td>
+                        </tr>
+                        <tr>
+                            <td><b>Text corner</b>:</td>
+                            <td>
+                                <select name="TextCorner" style="width:430px;text-align:right">
+                                    {{.TextCorner}}
                                 </select>
                             </td>
                         </tr>
+                        <tr>
+                            <td><b>Text color</b>:</td>
+                            <td>
+                                <input type="color" name="TextColor" style="width:430px" value="{{.TextColor}}">
+                            </td>
+                        </tr>
+                        <tr>
+                            <td><b>Text scale</b>:</td>
+                            <td><input type="number" name="TextScale" style="text-align:right;width:430px" value="{{.TextScale}}"></td>
+                        </tr>
+                        <tr>
+                            <td><input type="checkbox" name="Optimize" value="1" {{.Optimize}}>
+                            <b>Optimize frames</b></td>
+                            <td></td>
+                        </tr>
                         <tr>
                             <td><input type="checkbox" name="Endless" value="1" {{.Endless}}>
                             <b>Endless animation</b></td>
@@ -340,6 +1334,23 @@ var gFormTemplate string = `
     <div>
         <center>
             <img src="data:image/gif;base64,{{.GIFData}}" alt=":("/>
+            {{if .RLEData}}
+            <br><small>Download the next generation as
+            <a href="data:text/plain;base64,{{.RLEData}}" download="googol.rle">RLE</a> or
+            <a href="data:text/plain;base64,{{.CellsData}}" download="googol.cells">plaintext</a>
+            (seed {{.SeedUsed}})</small>
+            {{end}}
+            {{if .Annotations}}
+            <br><table border=1 style="border-collapse:collapse">
+                <tr><th>Position</th><th>Size</th><th>Population</th><th>Kind</th><th>Period</th><th>Velocity</th><th>Object</th></tr>
+                {{range .Annotations}}
+                <tr>
+                    <td>{{.X}},{{.Y}}</td><td>{{.Width}}x{{.Height}}</td><td>{{.Population}}</td><td>{{.Kind}}</td>
+                    <td>{{if .Period}}{{.Period}}{{end}}</td><td>{{.Velocity}}</td><td>{{if .Name}}{{.Name}}{{else}}{{.Apgcode}}{{end}}</td>
+                </tr>
+                {{end}}
+            </table>
+            {{end}}
         </center>
     </div>
     <footer>
@@ -351,7 +1362,6 @@ var gFormTemplate string = `
 `
 
 func main() {
-	rand.Seed(time.Now().UTC().UnixNano())
 	var exitCode int = 0
 	if len(os.Args) < 2 {
 		fmt.Fprintf(os.Stdout, "usage: googol <command>\n")
@@ -387,12 +1397,48 @@ func helpGIF() int {
 	fmt.Fprintf(os.Stdout, "usage: googol gif [--board-with=<n> --board-height=<n> --gif-with=<n>\n"+
 		"                   --gif-height=<n> --delay=<n> --cell-size-in-px=<n>\n"+
 		"                   --gen-total=<n> --bk-color=<color> --fg-color=<color>\n"+
-		"                   --endless] --out=<file-path> [initial-board-state]\n\n"+
+		"                   --rule=<rulestring> --topology=<name> --engine=<name>\n"+
+		"                   --viewport=<x>,<y>[,<w>,<h>] --fit --follow\n"+
+		"                   --gen-step=<n> --workers=<n>\n"+
+		"                   --pattern=<file-path> --pattern-at=<x>,<y>\n"+
+		"                   --stop-on-cycle --stats=<file-path>\n"+
+		"                   --stats-format=<csv|json> --population-strip=<n>\n"+
+		"                   --population-chart=<file-path> --color-mode=<name>\n"+
+		"                   --aged-color=<color> --trail-length=<n>\n"+
+		"                   --palette=<file-path> --seed=<n>\n"+
+		"                   --grid=<color> --grid-thickness=<n>\n"+
+		"                   --cell-shape=<name> --cell-padding=<n>\n"+
+		"                   --caption=<text> --show-generation --show-population\n"+
+		"                   --text-corner=<corner> --text-color=<color>\n"+
+		"                   --text-scale=<n>\n"+
+		"                   --soup=<w>x<h>@<density>[:<symmetry>]\n"+
+		"                   --use=<name>[@<x>,<y>][:<transform>]...\n"+
+		"                   --optimize --report-savings --endless]\n"+
+		"                   --out=<file-path>\n"+
+		"                   [initial-board-state]\n\n"+
 		"                  or\n\n"+
 		"       googol gif [--board-with=<n> --board-height=<n> --gif-with=<n>\n"+
 		"                   --gif-height=<n> --delay=<n> --cell-size-in-px=<n>\n"+
 		"                   --gen-total=<n> --bk-color=<color> --fg-color=<color>\n"+
-		"                   --endless] > <file-path> [initial-board-state]\n"+
+		"                   --rule=<rulestring> --topology=<name> --engine=<name>\n"+
+		"                   --viewport=<x>,<y>[,<w>,<h>] --fit --follow\n"+
+		"                   --gen-step=<n> --workers=<n>\n"+
+		"                   --pattern=<file-path> --pattern-at=<x>,<y>\n"+
+		"                   --stop-on-cycle --stats=<file-path>\n"+
+		"                   --stats-format=<csv|json> --population-strip=<n>\n"+
+		"                   --population-chart=<file-path> --color-mode=<name>\n"+
+		"                   --aged-color=<color> --trail-length=<n>\n"+
+		"                   --palette=<file-path> --seed=<n>\n"+
+		"                   --grid=<color> --grid-thickness=<n>\n"+
+		"                   --cell-shape=<name> --cell-padding=<n>\n"+
+		"                   --caption=<text> --show-generation --show-population\n"+
+		"                   --text-corner=<corner> --text-color=<color>\n"+
+		"                   --text-scale=<n>\n"+
+		"                   --soup=<w>x<h>@<density>[:<symmetry>]\n"+
+		"                   --use=<name>[@<x>,<y>][:<transform>]...\n"+
+		"                   --optimize --report-savings --endless]\n"+
+		"                   > <file-path>\n"+
+		"                   [initial-board-state]\n"+
 		"Defaults:\n\n"+
 		"\t* --board-width = %s\n"+
 		"\t* --board-height = %s\n"+
@@ -401,20 +1447,270 @@ func helpGIF() int {
 		"\t* --delay = %sms\n"+
 		"\t* --cell-size-in-px = --gif-width / 8\n"+
 		"\t* --gen-total = %s\n"+
+		"\t* --gen-step = %s\n"+
 		"\t* --out = stdout\n"+
 		"\t* --bk-color = %s\n"+
 		"\t* --fg-color = %s\n"+
+		"\t* --rule = %s\n"+
+		"\t* --topology = %s\n"+
+		"\t* --engine = %s\n"+
+		"\t* --viewport = %s\n"+
+		"\t* --fit = false\n"+
+		"\t* --follow = false\n"+
+		"\t* --workers = GOMAXPROCS\n"+
+		"\t* --pattern = <empty>\n"+
+		"\t* --pattern-at = %s\n"+
+		"\t* --stop-on-cycle = false\n"+
+		"\t* --stats = <empty>\n"+
+		"\t* --stats-format = %s\n"+
+		"\t* --population-strip = %s\n"+
+		"\t* --population-chart = <empty>\n"+
+		"\t* --color-mode = %s\n"+
+		"\t* --aged-color = %s\n"+
+		"\t* --trail-length = %s\n"+
+		"\t* --grid = <empty>\n"+
+		"\t* --grid-thickness = %s\n"+
+		"\t* --cell-shape = %s\n"+
+		"\t* --cell-padding = %s\n"+
+		"\t* --caption = <empty>\n"+
+		"\t* --show-generation = false\n"+
+		"\t* --show-population = false\n"+
+		"\t* --text-corner = %s\n"+
+		"\t* --text-color = --fg-color\n"+
+		"\t* --text-scale = %s\n"+
+		"\t* --palette = <empty>\n"+
+		"\t* --seed = <taken from the clock>\n"+
+		"\t* --soup = <empty>\n"+
+		"\t* --use = <empty>\n"+
+		"\t* --optimize = false\n"+
+		"\t* --report-savings = false\n"+
 		"\t* --endless = false\n"+
 		"Notes:\n\n"+
 		"\t* The file path passed through --out is overwritten without\n"+
 		"\t  any prompt.\n"+
-		"\t* The available colors are: 'black', 'blue', 'cyan', 'gray'\n"+
-		"\t  'green', 'magenta', 'red', 'white', 'yellow'. You can also\n"+
-		"\t  use 'random' or 'any' instead.\n"+
+		"\t* A color can be given by any CSS color name ('black', 'navy',\n"+
+		"\t  'tomato', 'rebeccapurple', etc), in form '#RRGGBB' or '#RGB',\n"+
+		"\t  or in form 'rgb(<r>,<g>,<b>)'. You can also use 'random' or\n"+
+		"\t  'any' instead.\n"+
 		"\t* [initial-board-state] stands for a list of options in form\n"+
 		"\t  '--<n>,<n>.', where <n>,<n> are the coordinates (x,y) of an\n"+
-		"\t  alive cell.\n", gDefaultBoardWidth, gDefaultBoardHeight, gDefaultDelay, gDefaultGenTotal,
-		gDefaultBkColor, gDefaultFgColor)
+		"\t  alive cell.\n"+
+		"\t* --rule accepts any Life-like rulestring in B/S form ('B36/S23')\n"+
+		"\t  or in S/B form ('23/36'). The names 'life', 'highlife', 'seeds',\n"+
+		"\t  'daynight', 'lifewithoutdeath', '2x2', 'maze', 'replicator',\n"+
+		"\t  'diamoeba', 'morley' and 'anneal' are also accepted.\n"+
+		"\t* --topology defines how the board edges are glued together:\n"+
+		"\t  'plane' (no gluing, outside cells are dead), 'torus', 'klein:x'\n"+
+		"\t  (left/right edges glued with a twist), 'klein:y' (top/bottom\n"+
+		"\t  edges glued with a twist), 'cross-surface' (both glued with a\n"+
+		"\t  twist) and 'sphere' (left edge glued to top edge, right edge\n"+
+		"\t  glued to bottom edge). The 'sphere' needs a square board.\n"+
+		"\t* --engine selects how the universe is stored: 'board' is a fixed\n"+
+		"\t  grid of --board-width x --board-height cells, 'bitboard' is the\n"+
+		"\t  same grid packing 64 cells per machine word (faster), 'sparse' is an\n"+
+		"\t  unbounded plane that only stores the alive cells (negative\n"+
+		"\t  coordinates are allowed, e.g. '---3,-4.') and 'hashlife' is also\n"+
+		"\t  unbounded but memoizes the evolution of repeated regions, being\n"+
+		"\t  able to jump millions of generations at once. The 'sparse' and\n"+
+		"\t  'hashlife' engines only work with the 'plane' topology and\n"+
+		"\t  without B0 rules.\n"+
+		"\t* --viewport is the universe coordinate shown at the GIF's top-left\n"+
+		"\t  corner. The visible area has --board-width x --board-height cells\n"+
+		"\t  unless its width and height are also given (<x>,<y>,<w>,<h>).\n"+
+		"\t* --fit scales the viewport up or down to the GIF's size, keeping\n"+
+		"\t  the cells square and centered. --cell-size-in-px is ignored.\n"+
+		"\t* --follow makes the viewport pan smoothly towards the alive cells,\n"+
+		"\t  keeping them centered. Spaceships never leave it and, along with\n"+
+		"\t  --fit, growing patterns zoom the viewport out.\n"+
+		"\t* --gen-step renders only every Nth generation, one frame is\n"+
+		"\t  produced for each <n> generations within --gen-total.\n"+
+		"\t* --workers is the number of goroutines computing the stripes of\n"+
+		"\t  the 'board' and 'bitboard' engines at the same time.\n"+
+		"\t* --pattern loads the initial state from a RLE, plaintext (.cells),\n"+
+		"\t  Life 1.05 or Life 1.06 file (the format is detected). Its top-left\n"+
+		"\t  corner is placed at --pattern-at. The rule defined by the file\n"+
+		"\t  is used unless --rule is also passed.\n"+
+		"\t* When the universe becomes extinct, still or periodic (even\n"+
+		"\t  translated, as spaceships) it is reported on stderr. With\n"+
+		"\t  --gen-step the period found is a multiple of <n>. Periods longer\n"+
+		"\t  than %d frames are not found.\n"+
+		"\t* --stop-on-cycle trims the GIF to exactly one period, dropping\n"+
+		"\t  the frames before the cycle, thus endless loops are seamless. A\n"+
+		"\t  universe that dies out gives a single empty frame.\n"+
+		"\t* --optimize encodes each frame as the rectangle that changed since\n"+
+		"\t  the previous one (unchanged pixels within it are transparent) and\n"+
+		"\t  merges identical frames extending their delay.\n"+
+		"\t* --report-savings tells on stderr the bytes saved by --optimize,\n"+
+		"\t  every frame is encoded twice to know it.\n"+
+		"\t* Frames are written as soon as they are drawn and only the last\n"+
+		"\t  %d frames are kept for finding cycles, thus memory does not\n"+
+		"\t  grow with --gen-total (but for one number per frame kept by\n"+
+		"\t  --population-strip). --stop-on-cycle and --population-strip\n"+
+		"\t  run the universe through on a copy first, taking twice the time.\n"+
+		"\t* --stats writes the statistics of each rendered generation to\n"+
+		"\t  a file, as the 'stats' command does.\n"+
+		"\t* --population-strip adds a strip of <n> pixels under each frame\n"+
+		"\t  with the population curve, a dotted line marks the current\n"+
+		"\t  generation.\n"+
+		"\t* --population-chart writes the population curve to a standalone\n"+
+		"\t  chart, a '.png' or '.svg' file.\n"+
+		"\t* --color-mode picks how cells are painted: 'binary' (alive cells\n"+
+		"\t  in --fg-color), 'age' (from --fg-color when newborn to\n"+
+		"\t  --aged-color after %d frames), 'trail' (dead cells leave ghosts\n"+
+		"\t  fading into --bk-color for --trail-length frames) and 'heat'\n"+
+		"\t  (white hot newborn cells cooling down to red, with dark red\n"+
+		"\t  ghosts). The --trail-length can be up to %d frames.\n"+
+		"\t* --palette reads colors from a file, one per line (lines starting\n"+
+		"\t  with ';' are comments). The first one is the background, the\n"+
+		"\t  second one the foreground. From the second one on they are the\n"+
+		"\t  stops of the gradient painting cells by age in the 'age' and\n"+
+		"\t  'heat' color modes.\n"+
+		"\t* --grid draws lines of --grid-thickness pixels in the given color\n"+
+		"\t  around the cells (only when the cells are bigger than the lines).\n"+
+		"\t* --cell-shape paints the cells as 'square', 'circle', 'rounded'\n"+
+		"\t  (square with rounded corners) or 'diamond', --cell-padding keeps\n"+
+		"\t  <n> pixels free around them.\n"+
+		"\t* --caption, --show-generation and --show-population write lines\n"+
+		"\t  of text on each frame (in this order) with a built-in 5x7 font.\n"+
+		"\t  The --text-corner can be 'top-left', 'top-right', 'bottom-left'\n"+
+		"\t  or 'bottom-right'. Each dot of the font takes --text-scale x\n"+
+		"\t  --text-scale pixels.\n"+
+		"\t* --seed feeds everything random (e.g. 'random' colors). When it\n"+
+		"\t  is not given the seed used is told on stderr, passing it back\n"+
+		"\t  reproduces the same GIF.\n"+
+		"\t* --soup fills a <w> x <h> region centered on the board with\n"+
+		"\t  random cells, each one alive with probability <density> (from\n"+
+		"\t  0 to 1). The symmetry can be C1 (none), C2, C4, D2, D4 or D8,\n"+
+		"\t  C4 and D8 require a square soup. The soup must fit the board.\n"+
+		"\t* --use puts a pattern of the library (see 'googol patterns') or\n"+
+		"\t  the --pattern itself (<name> = 'pattern') at <x>,<y> (%s by\n"+
+		"\t  default). The transforms are rotations (clockwise in degrees: 0,\n"+
+		"\t  90, 180 or 270) and reflections ('flip-x' mirrors from left to\n"+
+		"\t  right, 'flip-y' from top to bottom), applied in the given order.\n"+
+		"\t  It can be passed many times, all placed patterns are united.\n"+
+		"\t  Once --use places 'pattern', --pattern-at is not used.\n"+
+		"\t* All placed patterns must fit the board. The 'sparse' and\n"+
+		"\t  'hashlife' engines take them anywhere, as long as they are not\n"+
+		"\t  bigger than the board.\n", gDefaultBoardWidth, gDefaultBoardHeight,
+		gDefaultDelay, gDefaultGenTotal, gDefaultGenStep, gDefaultBkColor, gDefaultFgColor, gDefaultRule, gDefaultTopology,
+		gDefaultEngine, gDefaultViewport, gDefaultPatternAt, gDefaultStatsFormat, gDefaultPopulationStrip, gDefaultColorMode,
+		gDefaultAgedColor, gDefaultTrailLength, gDefaultGridThickness, gDefaultCellShape, gDefaultCellPadding,
+		gDefaultTextCorner, gDefaultTextScale,
+		gMaxCycleFrames, gMaxCycleFrames, gAgeGradientLength, gMaxTrailLength, gDefaultPatternAt)
+	return 0
+}
+
+func helpExport() int {
+	fmt.Fprintf(os.Stdout, "usage: googol export [--gen=<n> --format=<rle|cells> --board-with=<n>\n"+
+		"                      --board-height=<n> --rule=<rulestring> --topology=<name>\n"+
+		"                      --engine=<name> --workers=<n> --pattern=<file-path>\n"+
+		"                      --pattern-at=<x>,<y> --seed=<n>\n"+
+		"                      --soup=<w>x<h>@<density>[:<symmetry>]\n"+
+		"                      --use=<name>[@<x>,<y>][:<transform>]...]\n"+
+		"                      --out=<file-path>\n"+
+		"                      [initial-board-state]\n"+
+		"Defaults:\n\n"+
+		"\t* --gen = %s\n"+
+		"\t* --format = %s\n"+
+		"\t* --out = stdout\n"+
+		"Notes:\n\n"+
+		"\t* The generation <n> of the universe is written as a pattern\n"+
+		"\t  cropped to the bounding box of its alive cells.\n"+
+		"\t* --format 'rle' writes RLE and 'cells' writes plaintext. The\n"+
+		"\t  rule is always written, plaintext gets it in a '!Rule:' line.\n"+
+		"\t* The other options and the initial state work as in the 'gif'\n"+
+		"\t  command.\n", gDefaultExportGen, gDefaultExportFormat)
+	return 0
+}
+
+func helpStats() int {
+	fmt.Fprintf(os.Stdout, "usage: googol stats [--gen-total=<n> --gen-step=<n> --stats-format=<csv|json>\n"+
+		"                     --board-with=<n> --board-height=<n> --rule=<rulestring>\n"+
+		"                     --topology=<name> --engine=<name> --workers=<n>\n"+
+		"                     --pattern=<file-path> --pattern-at=<x>,<y> --seed=<n>\n"+
+		"                     --soup=<w>x<h>@<density>[:<symmetry>]\n"+
+		"                     --use=<name>[@<x>,<y>][:<transform>]...]\n"+
+		"                     --out=<file-path>\n"+
+		"                     [initial-board-state]\n"+
+		"Defaults:\n\n"+
+		"\t* --gen-total = %s\n"+
+		"\t* --gen-step = %s\n"+
+		"\t* --stats-format = %s\n"+
+		"\t* --out = stdout\n"+
+		"Notes:\n\n"+
+		"\t* The universe is evolved without rendering anything. For each\n"+
+		"\t  generation (every <n> generations with --gen-step) it is written\n"+
+		"\t  the population, the births, the deaths, the bounding box of the\n"+
+		"\t  alive cells and the change rate ((births + deaths) / previous\n"+
+		"\t  population).\n"+
+		"\t* The other options and the initial state work as in the 'gif'\n"+
+		"\t  command.\n", gDefaultGenTotal, gDefaultGenStep, gDefaultStatsFormat)
+	return 0
+}
+
+func helpCensus() int {
+	fmt.Fprintf(os.Stdout, "usage: googol census [--soups=<n> --soup=<w>x<h>@<density>[:<symmetry>]\n"+
+		"                      --rule=<rulestring> --max-gen=<n> --census-format=<table|json>\n"+
+		"                      --workers=<n> --seed=<n>]\n"+
+		"                      --out=<file-path>\n"+
+		"Defaults:\n\n"+
+		"\t* --soups = %s\n"+
+		"\t* --soup = %s\n"+
+		"\t* --rule = %s\n"+
+		"\t* --max-gen = %s\n"+
+		"\t* --census-format = %s\n"+
+		"\t* --workers = GOMAXPROCS\n"+
+		"\t* --seed = <taken from the clock>\n"+
+		"\t* --out = stdout\n"+
+		"Notes:\n\n"+
+		"\t* Each soup runs in an unbounded universe until it repeats itself\n"+
+		"\t  in place, but for the spaceships flying away. Soups not stable\n"+
+		"\t  after --max-gen generations are counted as unstable.\n"+
+		"\t* Soups can be up to %dx%d cells.\n"+
+		"\t* The remaining ash is split into objects (cells apart by at most\n"+
+		"\t  one dead cell belong to the same object, unless its touching\n"+
+		"\t  pieces evolve apart as in a traffic light). Each object is told\n"+
+		"\t  by its apgcode, the same for all of its phases, rotations and\n"+
+		"\t  reflections. 'xs<n>' are still lifes of <n> cells, 'xp<n>'\n"+
+		"\t  oscillators of period <n> and 'xq<n>' spaceships of period <n>.\n"+
+		"\t* Objects not repeating themselves alone within %d generations\n"+
+		"\t  are counted as 'unclassified'.\n", gDefaultCensusSoups, gDefaultCensusSoup, gDefaultRule,
+		gDefaultCensusMaxGen, gDefaultCensusFormat, gMaxBoardWidth, gMaxBoardHeight, gMaxObjectPeriod)
+	return 0
+}
+
+func helpIdentify() int {
+	fmt.Fprintf(os.Stdout, "usage: googol identify [--pattern=<file-path> --pattern-at=<x>,<y> --rule=<rulestring>\n"+
+		"                        --gen=<n> --gap=<n> --identify-format=<table|json>]\n"+
+		"                        --out=<file-path>\n"+
+		"                        [initial-board-state]\n"+
+		"Defaults:\n\n"+
+		"\t* --pattern-at = %s\n"+
+		"\t* --rule = %s (or the rule of the pattern)\n"+
+		"\t* --gen = %s\n"+
+		"\t* --gap = %s\n"+
+		"\t* --identify-format = %s\n"+
+		"\t* --out = stdout\n"+
+		"Notes:\n\n"+
+		"\t* The alive cells of the generation <n> are split into islands,\n"+
+		"\t  cells apart by at most --gap dead cells belong to the same\n"+
+		"\t  island (unless its touching pieces evolve apart).\n"+
+		"\t* Each island is evolved alone and told as a still life, an\n"+
+		"\t  oscillator (with its period), a spaceship (with its period and\n"+
+		"\t  velocity) or as unclassified when it does not repeat itself\n"+
+		"\t  within %d generations. The usual objects are also named.\n"+
+		"\t* The universe is unbounded, thus B0 rules are not supported.\n", gDefaultPatternAt, gDefaultRule,
+		gDefaultExportGen, gDefaultIdentifyGap, gDefaultIdentifyFormat, gMaxObjectPeriod)
+	return 0
+}
+
+func helpPatterns() int {
+	fmt.Fprintf(os.Stdout, "usage: googol patterns\n"+
+		"Notes:\n\n"+
+		"\t* Lists the patterns of the library. Any of them can be put on\n"+
+		"\t  the board by 'gif', 'export', 'stats' and 'httpd' through\n"+
+		"\t  --use=<name>[@<x>,<y>][:<transform>]..., as many times as\n"+
+		"\t  needed (see 'googol help gif').\n")
 	return 0
 }
 
@@ -431,6 +1727,10 @@ func helpHttpd() int {
 		"\t* --form-template = (some lousy default HTML)\n"+
 		"\t* --max-board-width = %d\n"+
 		"\t* --max-board-height = %d\n"+
+		"\t* --max-gen-total = %d\n"+
+		"\t* --workers = %d\n"+
+		"\t* --seed = <taken from the clock on each request>\n"+
+		"\t* --gap = %s\n"+
 		"Notes:\n\n"+
 		"\t* When https is requested the default port is 443.\n"+
 		"\t* In order to gracefully stop the server send to the process\n"+
@@ -439,13 +1739,33 @@ func helpHttpd() int {
 		"\t* The defaults for the game and gifs are the same of the 'gif'\n"+
 		"\t  command.\n"+
 		"\t* If you want to set new defaults for the game or gifs\n"+
-		"\t  use the same options available in 'gif' command.\n", gDefaultPort, gDefaultAddr, gMaxBoardWidth,
-		gMaxBoardHeight)
+		"\t  use the same options available in 'gif' command.\n"+
+		"\t* --max-board-width, --max-board-height and --max-gen-total\n"+
+		"\t  bound every request whatever the engine is: the board, the\n"+
+		"\t  viewport, the generation total and the generations per frame.\n"+
+		"\t* --workers is shared by all requests, the default is the\n"+
+		"\t  number of CPUs usable by the process (GOMAXPROCS).\n"+
+		"\t* --seed fills the form's seed field. An empty seed field means\n"+
+		"\t  a new seed for each request, the seed used is shown under the\n"+
+		"\t  GIF.\n"+
+		"\t* --soup fills the form's soup field.\n"+
+		"\t* Besides cells, the initial state field accepts placements as\n"+
+		"\t  in --use (e.g. 'glider@10,10:90:flip-y').\n"+
+		"\t* --use fills the form's library patterns field (separated by\n"+
+		"\t  spaces).\n"+
+		"\t* --identify checks the form's identify field, when checked the\n"+
+		"\t  objects of the generation that follows the last frame are\n"+
+		"\t  listed under the GIF. --gap works as in 'identify' command.\n"+
+		"\t* '/googol.gif' takes the same fields of the form and replies\n"+
+		"\t  the bare GIF, streamed frame by frame. Errors are replied as\n"+
+		"\t  plain text with status 400.\n", gDefaultPort, gDefaultAddr,
+		gMaxBoardWidth, gMaxBoardHeight, gMaxGenTotal, gWorkersNr, gDefaultIdentifyGap)
 	return 0
 }
 
 func httpdGIFdumper() int {
 	http.HandleFunc("/googol", httpdHandler)
+	http.HandleFunc("/googol.gif", httpdGIFHandler)
 	var err error
 	gMaxBoardWidth, err = strconv.Atoi(getOption("max-board-width", fmt.Sprintf("%d", gMaxBoardWidth)))
 	if err != nil || gMaxBoardWidth <= 0 {
@@ -457,6 +1777,25 @@ func httpdGIFdumper() int {
 		fmt.Fprintf(os.Stderr, "ERROR: option --max-board-height must be a valid positive integer.\n")
 		os.Exit(1)
 	}
+	gMaxGenTotal, err = strconv.Atoi(getOption("max-gen-total", fmt.Sprintf("%d", gMaxGenTotal)))
+	if err != nil || gMaxGenTotal <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option --max-gen-total must be a valid positive integer.\n")
+		os.Exit(1)
+	}
+	gIdentifyGap, err = strconv.Atoi(getOption("gap", fmt.Sprintf("%d", gIdentifyGap)))
+	if err != nil || gIdentifyGap < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option --gap must be a valid non-negative integer.\n")
+		os.Exit(1)
+	}
+	if _, _, err = makeRandom(getOption("seed", "")); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option --seed must be a valid integer.\n")
+		os.Exit(1)
+	}
+	gWorkersNr, err = strconv.Atoi(getOption("workers", fmt.Sprintf("%d", gWorkersNr)))
+	if err != nil || gWorkersNr <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option --workers must be a valid positive integer.\n")
+		os.Exit(1)
+	}
 	var googol func()
 	if !getBoolOption("https", false) {
 		googol = func() {
@@ -500,6 +1839,14 @@ func httpdGIFdumper() int {
 		}
 		gFormTemplate = string(buf)
 	}
+	if patternPath := getOption("pattern", ""); len(patternPath) > 0 {
+		buf, err := ioutil.ReadFile(patternPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Unable to read pattern: %v.\n", err)
+			os.Exit(1)
+		}
+		gDefaultPatternData = string(buf)
+	}
 	go googol() // OoOOoOoOOOL...
 	sigintWatchdog := make(chan os.Signal, 1)
 	signal.Notify(sigintWatchdog, os.Interrupt)
@@ -510,10 +1857,35 @@ func httpdGIFdumper() int {
 }
 
 func httpdHandler(w http.ResponseWriter, r *http.Request) {
+	serveGoogolRequest(w, r, false)
+}
+
+func httpdGIFHandler(w http.ResponseWriter, r *http.Request) {
+	serveGoogolRequest(w, r, true)
+}
+
+func serveGoogolRequest(w http.ResponseWriter, r *http.Request, streamGIF bool) {
 	responseTemplate := template.Must(template.New("escape").Parse(gFormTemplate))
+	gifOut := w
+	if streamGIF {
+		responseTemplate = template.Must(template.New("error").Parse(gGIFErrorTemplate))
+		w = &httpdErrorWriter{ResponseWriter: w}
+	}
 	userData := newGoogolRequest(r)
-	var boardWidth, boardHeight, gifWidth, gifHeight, delay, cellSizeInPx, genNr int
-	var err error
+	random, seed, err := makeRandom(userData.Seed)
+	if err != nil {
+		userData.Error = "ERROR: Seed must be a valid integer."
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	userData.SeedUsed = seed
+	userData.SelectedBkColor = getColorOption(&userData.BkColor, random)
+	userData.SelectedFgColor = getColorOption(&userData.FgColor, random)
+	userData.SelectedAgedColor = getColorOption(&userData.AgedColor, random)
+	userData.SelectedGridColor = getColorOption(&userData.GridColor, random)
+	userData.SelectedTextColor = getColorOption(&userData.TextColor, random)
+	var boardWidth, boardHeight, gifWidth, gifHeight, delay, cellSizeInPx, genNr, genStep, stripHeight, trailLength,
+		gridThickness, cellPadding, textScale int
 	boardWidth, err = strconv.Atoi(userData.BoardWidth)
 	if err != nil || boardWidth <= 0 || boardWidth > gMaxBoardWidth {
 		userData.Error = template.HTML(fmt.Sprintf("ERROR: Board width must be a valid positive "+
@@ -555,20 +1927,209 @@ func httpdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	genNr, err = strconv.Atoi(userData.GenTotal)
-	if err != nil || genNr <= 0 {
-		userData.Error = "ERROR: Generation total must be a valid positive interger."
+	if err != nil || genNr <= 0 || genNr > gMaxGenTotal {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: Generation total must be a valid positive integer "+
+			"between 1 and %d.", gMaxGenTotal))
 		responseTemplate.Execute(w, userData)
 		return
 	}
-	cells := makeGameBoard(boardWidth, boardHeight)
-	setBigBangGeneration(cells, userData.InitialState)
-	gifBuf := bytes.NewBufferString("")
-	makeGIFofLife(gifBuf, userData.SelectedBkColor, userData.SelectedFgColor, gifWidth, gifHeight, delay, userData.Endless == "checked",
-		cellSizeInPx, cells, genNr)
-	userData.GIFData = base64.StdEncoding.EncodeToString(gifBuf.Bytes())
+	genStep, err = strconv.Atoi(userData.GenStep)
+	if err != nil || genStep <= 0 || genStep > gMaxGenTotal {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: Generations per frame must be a valid positive integer "+
+			"between 1 and %d.", gMaxGenTotal))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	makeColorizer, ok := gAvailColorModes[userData.SelectedColorMode]
+	if !ok {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: '%s' is not a known color mode.",
+			template.HTMLEscapeString(userData.SelectedColorMode)))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	trailLength, err = strconv.Atoi(userData.TrailLength)
+	if err != nil || trailLength < 0 || trailLength > gMaxTrailLength {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: Trail length must be a valid integer between 0 and %d.",
+			gMaxTrailLength))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	shape, ok := gAvailCellShapes[userData.SelectedCellShape]
+	if !ok {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: '%s' is not a known cell shape.",
+			template.HTMLEscapeString(userData.SelectedCellShape)))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	cellPadding, err = strconv.Atoi(userData.CellPadding)
+	if err != nil || cellPadding < 0 || cellPadding > 100 {
+		userData.Error = "ERROR: Cell padding must be a valid integer between 0 and 100."
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	gridThickness, err = strconv.Atoi(userData.GridThickness)
+	if err != nil || gridThickness <= 0 || gridThickness > 100 {
+		userData.Error = "ERROR: Grid thickness must be a valid integer between 1 and 100."
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	if _, ok := gAvailTextCorners[userData.SelectedTextCorner]; !ok {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: '%s' is not a known text corner.",
+			template.HTMLEscapeString(userData.SelectedTextCorner)))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	textScale, err = strconv.Atoi(userData.TextScale)
+	if err != nil || textScale <= 0 || textScale > 20 {
+		userData.Error = "ERROR: Text scale must be a valid integer between 1 and 20."
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	stripHeight, err = strconv.Atoi(userData.PopulationStrip)
+	if err != nil || stripHeight < 0 || stripHeight > 200 {
+		userData.Error = "ERROR: Population strip height must be a valid integer between 0 and 200."
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	pattern, err := parsePattern(userData.Pattern, boardWidth, boardHeight)
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	patternAt, err := parseCoords(userData.PatternAt)
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: Pattern at: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	if len(pattern.rule) > 0 {
+		userData.Rule = pattern.rule
+	}
+	rule, err := parseRule(userData.Rule)
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	if userData.Identify == "checked" && rule.borns(0) {
+		userData.Error = "ERROR: B0 rules are not supported when identifying objects."
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	topology, err := parseTopology(userData.SelectedTopology, boardWidth, boardHeight)
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	makeUniverse, ok := gAvailEngines[userData.SelectedEngine]
+	if !ok {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: '%s' is not a known engine.",
+			template.HTMLEscapeString(userData.SelectedEngine)))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	universe, err := makeUniverse(boardWidth, boardHeight, rule, topology, gWorkersNr)
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	viewport, err := getViewport(userData.Viewport, boardWidth, boardHeight)
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	if viewport.Dx() > gMaxBoardWidth || viewport.Dy() > gMaxBoardHeight {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: The viewport can not be bigger than %dx%d.",
+			gMaxBoardWidth, gMaxBoardHeight))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	var soup lifePattern
+	if len(userData.Soup) > 0 {
+		if soup, err = makeSoup(userData.Soup, boardWidth, boardHeight, random); err != nil {
+			userData.Error = template.HTML(fmt.Sprintf("ERROR: Soup %s.", template.HTMLEscapeString(err.Error())))
+			responseTemplate.Execute(w, userData)
+			return
+		}
+	}
+	placements := strings.Fields(userData.Use)
+	for _, state := range userData.InitialState {
+		if _, _, isCell := getCellCoords(state); !isCell {
+			placements = append(placements, state)
+		}
+	}
+	placed, err := placePatterns(placements, pattern, patternAt)
+	if err == nil {
+		err = checkPatternFits(placed, boardWidth, boardHeight, gUnboundedEngines[userData.SelectedEngine])
+	}
+	if err != nil {
+		userData.Error = template.HTML(fmt.Sprintf("ERROR: %s.", template.HTMLEscapeString(err.Error())))
+		responseTemplate.Execute(w, userData)
+		return
+	}
+	setBigBangGeneration(universe, userData.InitialState, placed, soup)
+	colorizer := makeColorizer(userData.SelectedBkColor, userData.SelectedFgColor, userData.SelectedAgedColor, nil,
+		trailLength)
+	camera := newLifeCamera(viewport, gifWidth, gifHeight, cellSizeInPx, userData.Fit == "checked",
+		userData.Follow == "checked")
+	style := &cellStyle{shape: shape, padding: cellPadding, gridThickness: gridThickness}
+	if userData.Grid == "checked" {
+		style.grid, style.gridIndex = true, colorizer.addColor(userData.SelectedGridColor)
+	}
+	var caption *frameCaption
+	if len(userData.Caption) > 0 || userData.ShowGeneration == "checked" || userData.ShowPopulation == "checked" {
+		caption = &frameCaption{text: userData.Caption, generation: userData.ShowGeneration == "checked",
+			population: userData.ShowPopulation == "checked", corner: userData.SelectedTextCorner,
+			colorIndex: colorizer.addColor(userData.SelectedTextColor), scale: textScale}
+	}
+	gifBuf := bytes.NewBufferString("")
+	client := &httpdClientWriter{out: gifBuf, request: r}
+	if streamGIF {
+		gifOut.Header().Set("Content-Type", "image/gif")
+		client.out = gifOut
+	}
+	_, _, err = makeGIFofLife(client, colorizer, gifWidth, gifHeight, delay, userData.Endless == "checked",
+		camera, style, caption, universe, genNr, genStep, userData.StopOnCycle == "checked", nil, stripHeight,
+		userData.Optimize == "checked", false)
+	if err != nil || streamGIF {
+		return
+	}
+	userData.GIFData = base64.StdEncoding.EncodeToString(gifBuf.Bytes())
+	lastGeneration := getPatternFromUniverse(universe, pattern.name, rule)
+	userData.RLEData = base64.StdEncoding.EncodeToString([]byte(makeRLEPatternData(lastGeneration)))
+	userData.CellsData = base64.StdEncoding.EncodeToString([]byte(makePlainTextPatternData(lastGeneration)))
+	if userData.Identify == "checked" {
+		userData.Annotations = getUniverseObjects(universe, rule, gIdentifyGap)
+	}
 	responseTemplate.Execute(w, userData)
 }
 
+func (writer *httpdErrorWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.wroteHeader = true
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *httpdClientWriter) Write(data []byte) (int, error) {
+	if err := writer.request.Context().Err(); err != nil {
+		return 0, err
+	}
+	return writer.out.Write(data)
+}
+
+func (writer *httpdClientWriter) Flush() {
+	if flusher, ok := writer.out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func newGoogolRequest(r *http.Request) GoogolRequest {
 	var usrData GoogolRequest
 	if err := r.ParseForm(); err != nil {
@@ -591,15 +2152,14 @@ func newGoogolRequest(r *http.Request) GoogolRequest {
 }
 
 func dumpGIF() int {
-	var err error
-	xNr, err := strconv.Atoi(getOption("board-width", gDefaultBoardWidth))
-	if err != nil || xNr < 0 {
-		fmt.Fprintf(os.Stderr, "ERROR: option board-width must be a valid positive integer.\n")
+	xNr, yNr, err := getBoardSizeFromOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
 		return 1
 	}
-	yNr, err := strconv.Atoi(getOption("board-height", gDefaultBoardHeight))
-	if err != nil || yNr < 0 {
-		fmt.Fprintf(os.Stderr, "ERROR: option board-heigh must be a valid positive integer.\n")
+	random, err := getRandomFromOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
 		return 1
 	}
 	gifWidth, err := strconv.Atoi(getOption("gif-width", fmt.Sprintf("%d", xNr)))
@@ -627,139 +2187,2971 @@ func dumpGIF() int {
 		fmt.Fprintf(os.Stderr, "ERROR: option gen-total must be a valid positive integer.\n")
 		return 1
 	}
-	cells := makeGameBoard(xNr, yNr)
-	setBigBangGeneration(cells, os.Args[2:])
-	makeGIFofLife(getOutput(),
-		getColor(getOption("bk-color", gDefaultBkColor)),
-		getColor(getOption("fg-color", gDefaultFgColor)),
+	generationStep, err := strconv.Atoi(getOption("gen-step", gDefaultGenStep))
+	if err != nil || generationStep <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option gen-step must be a valid positive integer.\n")
+		return 1
+	}
+	writeStats, ok := gAvailStatsFormats[getOption("stats-format", gDefaultStatsFormat)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option stats-format must be 'csv' or 'json'.\n")
+		return 1
+	}
+	makeColorizer, ok := gAvailColorModes[getOption("color-mode", gDefaultColorMode)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option color-mode must be 'binary', 'age', 'trail' or 'heat'.\n")
+		return 1
+	}
+	bkColor, err := parseColor(getOption("bk-color", gDefaultBkColor), random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option bk-color: %v.\n", err)
+		return 1
+	}
+	fgColor, err := parseColor(getOption("fg-color", gDefaultFgColor), random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option fg-color: %v.\n", err)
+		return 1
+	}
+	agedColor, err := parseColor(getOption("aged-color", gDefaultAgedColor), random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option aged-color: %v.\n", err)
+		return 1
+	}
+	var stops []color.Color
+	if palettePath := getOption("palette", ""); len(palettePath) > 0 {
+		palette, err := loadPalette(palettePath, random)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: option palette: %v.\n", err)
+			return 1
+		}
+		bkColor, fgColor, stops = palette[0], palette[1], palette[1:]
+	}
+	trailLength, err := strconv.Atoi(getOption("trail-length", gDefaultTrailLength))
+	if err != nil || trailLength < 0 || trailLength > gMaxTrailLength {
+		fmt.Fprintf(os.Stderr, "ERROR: option trail-length must be a valid integer between 0 and %d.\n", gMaxTrailLength)
+		return 1
+	}
+	shape, ok := gAvailCellShapes[getOption("cell-shape", gDefaultCellShape)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option cell-shape must be 'square', 'circle', 'rounded' or 'diamond'.\n")
+		return 1
+	}
+	cellPadding, err := strconv.Atoi(getOption("cell-padding", gDefaultCellPadding))
+	if err != nil || cellPadding < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option cell-padding must be a valid non-negative integer.\n")
+		return 1
+	}
+	gridThickness, err := strconv.Atoi(getOption("grid-thickness", gDefaultGridThickness))
+	if err != nil || gridThickness <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option grid-thickness must be a valid positive integer.\n")
+		return 1
+	}
+	var gridColor color.Color
+	if gridOption := getOption("grid", ""); len(gridOption) > 0 {
+		if gridColor, err = parseColor(gridOption, random); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: option grid: %v.\n", err)
+			return 1
+		}
+	}
+	textCorner := getOption("text-corner", gDefaultTextCorner)
+	if _, ok := gAvailTextCorners[textCorner]; !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option text-corner must be 'top-left', 'top-right', 'bottom-left' or 'bottom-right'.\n")
+		return 1
+	}
+	textColor, err := parseColor(getOption("text-color", getOption("fg-color", gDefaultFgColor)), random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option text-color: %v.\n", err)
+		return 1
+	}
+	textScale, err := strconv.Atoi(getOption("text-scale", gDefaultTextScale))
+	if err != nil || textScale <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option text-scale must be a valid positive integer.\n")
+		return 1
+	}
+	stripHeight, err := strconv.Atoi(getOption("population-strip", gDefaultPopulationStrip))
+	if err != nil || stripHeight < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option population-strip must be a valid non-negative integer.\n")
+		return 1
+	}
+	var stats *lifeStatsTracker
+	statsPath := getOption("stats", "")
+	chartPath := getOption("population-chart", "")
+	if _, ok := gAvailChartFormats[strings.ToLower(filepath.Ext(chartPath))]; len(chartPath) > 0 && !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option population-chart must be a '.png' or '.svg' file path.\n")
+		return 1
+	}
+	if len(statsPath) > 0 || len(chartPath) > 0 {
+		stats = newLifeStatsTracker()
+	}
+	universe, _, _, err := getUniverseFromOptions(xNr, yNr, random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	viewport, err := getViewport(getOption("viewport", gDefaultViewport), xNr, yNr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option viewport: %v.\n", err)
+		return 1
+	}
+	colorizer := makeColorizer(bkColor, fgColor, agedColor, stops, trailLength)
+	style := &cellStyle{shape: shape, padding: cellPadding, gridThickness: gridThickness}
+	if gridColor != nil {
+		style.grid, style.gridIndex = true, colorizer.addColor(gridColor)
+	}
+	var caption *frameCaption
+	showGeneration := getBoolOption("show-generation", gDefaultShowGeneration)
+	showPopulation := getBoolOption("show-population", gDefaultShowPopulation)
+	if text := getOption("caption", ""); len(text) > 0 || showGeneration || showPopulation {
+		caption = &frameCaption{text: text, generation: showGeneration, population: showPopulation,
+			corner: textCorner, colorIndex: colorizer.addColor(textColor), scale: textScale}
+	}
+	cycle, savings, err := makeGIFofLife(getOutput(),
+		colorizer,
 		gifWidth, gifHeight,
 		delay,
 		getBoolOption("endless", gDefaultEndless),
-		cellSizeInPixels, cells, generationNr)
+		newLifeCamera(viewport, gifWidth, gifHeight, cellSizeInPixels, getBoolOption("fit", gDefaultFit),
+			getBoolOption("follow", gDefaultFollow)), style, caption,
+		universe, generationNr, generationStep,
+		getBoolOption("stop-on-cycle", gDefaultStopOnCycle), stats, stripHeight,
+		getBoolOption("optimize", gDefaultOptimize), getBoolOption("report-savings", gDefaultReportSavings))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	if cycle != nil {
+		fmt.Fprintf(os.Stderr, "INFO: %v.\n", cycle)
+	}
+	if savings != nil {
+		fmt.Fprintf(os.Stderr, "INFO: %v.\n", savings)
+	}
+	if len(chartPath) > 0 {
+		if err = writePopulationChart(chartPath, stats.records, bkColor, fgColor); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: option population-chart: %v.\n", err)
+			return 1
+		}
+	}
+	if len(statsPath) > 0 {
+		statsFile, err := os.Create(statsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+			return 1
+		}
+		defer statsFile.Close()
+		if err = writeStats(statsFile, stats.records); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: option stats: %v.\n", err)
+			return 1
+		}
+	}
 	return 0
 }
 
-func drawAliveCell(frame *image.Paletted, x, y, cellSizeInPixels, fgColorIndex int) {
-	for xtemp := 0; xtemp < cellSizeInPixels; xtemp++ {
-		for ytemp := 0; ytemp < cellSizeInPixels; ytemp++ {
-			frame.SetColorIndex(x+xtemp, y+ytemp, uint8(fgColorIndex))
-		}
+func exportPattern() int {
+	xNr, yNr, err := getBoardSizeFromOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	random, err := getRandomFromOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	generationNr, err := strconv.Atoi(getOption("gen", gDefaultExportGen))
+	if err != nil || generationNr < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option gen must be a valid non-negative integer.\n")
+		return 1
+	}
+	makePatternData, ok := gAvailExportFormats[getOption("format", gDefaultExportFormat)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option format must be 'rle' or 'cells'.\n")
+		return 1
+	}
+	universe, rule, pattern, err := getUniverseFromOptions(xNr, yNr, random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
 	}
+	universe.nextGenerations(generationNr)
+	fmt.Fprint(getOutput(), makePatternData(getPatternFromUniverse(universe, pattern.name, rule)))
+	return 0
 }
 
-func getOutput() io.Writer {
-	out := getOption("out", "")
-	if len(out) == 0 {
-		return os.Stdout
+func dumpStats() int {
+	xNr, yNr, err := getBoardSizeFromOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
 	}
-	f, err := os.Create(out)
+	random, err := getRandomFromOptions()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
-		os.Exit(1)
+		return 1
 	}
-	return f
+	generationNr, err := strconv.Atoi(getOption("gen-total", gDefaultGenTotal))
+	if err != nil || generationNr <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option gen-total must be a valid positive integer.\n")
+		return 1
+	}
+	generationStep, err := strconv.Atoi(getOption("gen-step", gDefaultGenStep))
+	if err != nil || generationStep <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option gen-step must be a valid positive integer.\n")
+		return 1
+	}
+	writeStats, ok := gAvailStatsFormats[getOption("stats-format", gDefaultStatsFormat)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option stats-format must be 'csv' or 'json'.\n")
+		return 1
+	}
+	universe, _, _, err := getUniverseFromOptions(xNr, yNr, random)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	stats := newLifeStatsTracker()
+	for g := 0; g < generationNr; g += generationStep {
+		stats.record(universe, g)
+		universe.nextGenerations(generationStep)
+	}
+	if err = writeStats(getOutput(), stats.records); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	return 0
 }
 
-func makeGIFofLife(out io.Writer,
-	bkColor, fgColor color.Color,
-	width, height,
-	delay int,
-	endless bool,
-	cellSizeInPixels int,
-	cells [][]byte, generationNr int) {
-	var gifImage gif.GIF
-	if endless {
-		gifImage = gif.GIF{LoopCount: 0}
-	} else {
-		gifImage = gif.GIF{LoopCount: 1}
+func takeCensus() int {
+	random, err := getRandomFromOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
 	}
-	xNr := len(cells)
-	yNr := len(cells[0])
-	for g := 0; g < generationNr; g++ {
-		frame := image.NewPaletted(image.Rect(0, 0, width, height), []color.Color{bkColor, fgColor})
-		xFrame := 0
-		for x := 0; x < xNr; x++ {
-			yFrame := 0
-			for y := 0; y < yNr; y++ {
-				if (cells[x][y] & 0x1) == 1 {
-					drawAliveCell(frame, xFrame, yFrame, cellSizeInPixels, 1)
+	soupsNr, err := strconv.Atoi(getOption("soups", gDefaultCensusSoups))
+	if err != nil || soupsNr <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option soups must be a valid positive integer.\n")
+		return 1
+	}
+	maxGenerationNr, err := strconv.Atoi(getOption("max-gen", gDefaultCensusMaxGen))
+	if err != nil || maxGenerationNr <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option max-gen must be a valid positive integer.\n")
+		return 1
+	}
+	writeCensus, ok := gAvailCensusFormats[getOption("census-format", gDefaultCensusFormat)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option census-format must be 'table' or 'json'.\n")
+		return 1
+	}
+	rule, err := parseRule(getOption("rule", gDefaultRule))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option rule: %v.\n", err)
+		return 1
+	}
+	if rule.borns(0) {
+		fmt.Fprintf(os.Stderr, "ERROR: option rule: B0 rules are not supported by census.\n")
+		return 1
+	}
+	workersNr, err := strconv.Atoi(getOption("workers", fmt.Sprintf("%d", runtime.GOMAXPROCS(0))))
+	if err != nil || workersNr <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option workers must be a valid positive integer.\n")
+		return 1
+	}
+	soupSpec := getOption("soup", gDefaultCensusSoup)
+	soups := make([]lifePattern, soupsNr)
+	for s := range soups {
+		if soups[s], err = makeSoup(soupSpec, gMaxBoardWidth, gMaxBoardHeight, random); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: option soup: %v.\n", err)
+			return 1
+		}
+	}
+	ashes := make([][]string, soupsNr)
+	stable := make([]bool, soupsNr)
+	runOnStripes(soupsNr, workersNr, func(first, last int) {
+		for s := first; s < last; s++ {
+			var ash lifePattern
+			if ash, stable[s] = getSoupAsh(soups[s], rule, maxGenerationNr); stable[s] {
+				for _, object := range splitIntoObjects(ash, rule, gCensusGap) {
+					ashes[s] = append(ashes[s], classifyObject(object, rule).Apgcode)
 				}
-				yFrame += cellSizeInPixels
 			}
-			xFrame += cellSizeInPixels
 		}
-		gifImage.Delay = append(gifImage.Delay, delay)
-		gifImage.Image = append(gifImage.Image, frame)
-		getNextGeneration(cells)
+	})
+	census := lifeCensus{Rule: rule.String(), Soup: soupSpec, Soups: soupsNr}
+	counts := make(map[string]int)
+	for s, ash := range ashes {
+		if !stable[s] {
+			census.UnstableSoups++
+		}
+		for _, apgcode := range ash {
+			counts[apgcode]++
+		}
 	}
-	gif.EncodeAll(out, &gifImage)
-}
-
-func makeGameBoard(xNr, yNr int) [][]byte {
-	var cells [][]byte
-	cells = make([][]byte, yNr)
-	for y := 0; y < yNr; y++ {
-		cells[y] = make([]byte, xNr)
+	for apgcode, count := range counts {
+		object := censusObject{Apgcode: apgcode, Count: count}
+		if census.Rule == gDefaultRule {
+			object.Name = gKnownObjectNames[apgcode]
+		}
+		census.Objects = append(census.Objects, object)
 	}
-	return cells
-}
-
-func getCellCoords(str string) (int, int) {
-	x := -1
-	y := -1
-	if strings.HasPrefix(str, "--") && strings.HasSuffix(str, ".") {
-		coords := strings.Split(str[2:len(str)-1], ",")
-		if len(coords) == 2 {
-			var err error
-			x, err = strconv.Atoi(coords[0])
-			if err == nil {
-				y, err = strconv.Atoi(coords[1])
-			}
+	sort.Slice(census.Objects, func(i, j int) bool {
+		if census.Objects[i].Count != census.Objects[j].Count {
+			return census.Objects[i].Count > census.Objects[j].Count
 		}
+		return census.Objects[i].Apgcode < census.Objects[j].Apgcode
+	})
+	if err = writeCensus(getOutput(), census); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
 	}
-	return x, y
+	return 0
 }
 
-func setBigBangGeneration(cells [][]byte, args []string) {
-	xNr := len(cells)
-	yNr := len(cells[0])
+func identifyObjects() int {
+	var pattern lifePattern
+	var err error
+	if patternPath := getOption("pattern", ""); len(patternPath) > 0 {
+		if pattern, err = loadPattern(patternPath, gMaxPatternSize, gMaxPatternSize); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: option pattern: %v.\n", err)
+			return 1
+		}
+	}
+	patternAt, err := parseCoords(getOption("pattern-at", gDefaultPatternAt))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option pattern-at: %v.\n", err)
+		return 1
+	}
+	defaultRule := gDefaultRule
+	if len(pattern.rule) > 0 {
+		defaultRule = pattern.rule
+	}
+	rule, err := parseRule(getOption("rule", defaultRule))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: option rule: %v.\n", err)
+		return 1
+	}
+	generationNr, err := strconv.Atoi(getOption("gen", gDefaultExportGen))
+	if err != nil || generationNr < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option gen must be a valid non-negative integer.\n")
+		return 1
+	}
+	gap, err := strconv.Atoi(getOption("gap", gDefaultIdentifyGap))
+	if err != nil || gap < 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: option gap must be a valid non-negative integer.\n")
+		return 1
+	}
+	writeObjects, ok := gAvailIdentifyFormats[getOption("identify-format", gDefaultIdentifyFormat)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: option identify-format must be 'table' or 'json'.\n")
+		return 1
+	}
+	if rule.borns(0) {
+		fmt.Fprintf(os.Stderr, "ERROR: option rule: B0 rules are not supported by identify.\n")
+		return 1
+	}
+	universe, _ := makeSparseUniverse(0, 0, rule, planeTopology, 1)
+	setBigBangGeneration(universe, os.Args[2:], pattern.translate(patternAt.X, patternAt.Y))
+	universe.nextGenerations(generationNr)
+	if err = writeObjects(getOutput(), getUniverseObjects(universe, rule, gap)); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	return 0
+}
+
+func listPatterns() int {
+	names := make([]string, 0, len(gPatternLibrary))
+	for name := range gPatternLibrary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := [][]string{{"name", "size", "population", "description"}}
+	for _, name := range names {
+		pattern, _ := parseRLEPattern(gPatternLibrary[name], gMaxPatternSize, gMaxPatternSize)
+		width, height := pattern.getSize()
+		rows = append(rows, []string{name, fmt.Sprintf("%dx%d", width, height), strconv.Itoa(len(pattern.cells)),
+			pattern.name})
+	}
+	if err := writeTable(os.Stdout, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		return 1
+	}
+	return 0
+}
+
+func getBoardSizeFromOptions() (int, int, error) {
+	xNr, err := strconv.Atoi(getOption("board-width", gDefaultBoardWidth))
+	if err != nil || xNr < 0 {
+		return 0, 0, fmt.Errorf("option board-width must be a valid positive integer")
+	}
+	yNr, err := strconv.Atoi(getOption("board-height", gDefaultBoardHeight))
+	if err != nil || yNr < 0 {
+		return 0, 0, fmt.Errorf("option board-heigh must be a valid positive integer")
+	}
+	return xNr, yNr, nil
+}
+
+func getUniverseFromOptions(xNr, yNr int, random *rand.Rand) (lifeUniverse, lifeRule, lifePattern, error) {
+	var pattern lifePattern
+	var err error
+	if patternPath := getOption("pattern", ""); len(patternPath) > 0 {
+		pattern, err = loadPattern(patternPath, xNr, yNr)
+		if err != nil {
+			return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option pattern: %v", err)
+		}
+	}
+	patternAt, err := parseCoords(getOption("pattern-at", gDefaultPatternAt))
+	if err != nil {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option pattern-at: %v", err)
+	}
+	defaultRule := gDefaultRule
+	if len(pattern.rule) > 0 {
+		defaultRule = pattern.rule
+	}
+	rule, err := parseRule(getOption("rule", defaultRule))
+	if err != nil {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option rule: %v", err)
+	}
+	topology, err := parseTopology(getOption("topology", gDefaultTopology), xNr, yNr)
+	if err != nil {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option topology: %v", err)
+	}
+	engine := getOption("engine", gDefaultEngine)
+	makeUniverse, ok := gAvailEngines[engine]
+	if !ok {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option engine must be 'board', 'bitboard', 'sparse' or 'hashlife'")
+	}
+	workersNr, err := strconv.Atoi(getOption("workers", fmt.Sprintf("%d", runtime.GOMAXPROCS(0))))
+	if err != nil || workersNr <= 0 {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option workers must be a valid positive integer")
+	}
+	universe, err := makeUniverse(xNr, yNr, rule, topology, workersNr)
+	if err != nil {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option engine: %v", err)
+	}
+	placed, err := placePatterns(getOptions("use"), pattern, patternAt)
+	if err != nil {
+		return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option use: %v", err)
+	}
+	if err = checkPatternFits(placed, xNr, yNr, gUnboundedEngines[engine]); err != nil {
+		return nil, lifeRule{}, lifePattern{}, err
+	}
+	patterns := []lifePattern{placed}
+	if soupSpec := getOption("soup", ""); len(soupSpec) > 0 {
+		soup, err := makeSoup(soupSpec, xNr, yNr, random)
+		if err != nil {
+			return nil, lifeRule{}, lifePattern{}, fmt.Errorf("option soup: %v", err)
+		}
+		patterns = append(patterns, soup)
+	}
+	setBigBangGeneration(universe, os.Args[2:], patterns...)
+	return universe, rule, pattern, nil
+}
+
+func drawAliveCell(frame *image.Paletted, area image.Rectangle, style *cellStyle, fgColorIndex int) {
+	if padded := area.Inset(style.padding); !padded.Empty() {
+		area = padded
+	}
+	visible := area.Intersect(frame.Rect)
+	for y := visible.Min.Y; y < visible.Max.Y; y++ {
+		for x := visible.Min.X; x < visible.Max.X; x++ {
+			if style.shape(x-area.Min.X, y-area.Min.Y, area.Dx(), area.Dy()) {
+				frame.SetColorIndex(x, y, uint8(fgColorIndex))
+			}
+		}
+	}
+}
+
+func fillArea(frame *image.Paletted, area image.Rectangle, colorIndex uint8) {
+	area = area.Intersect(frame.Rect)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			frame.SetColorIndex(x, y, colorIndex)
+		}
+	}
+}
+
+func drawGrid(frame *image.Paletted, camera *lifeCamera, style *cellStyle) {
+	if !style.hasGrid(camera) {
+		return
+	}
+	visible := camera.getVisibleArea()
+	thickness := image.Pt(style.gridThickness, style.gridThickness)
+	gridArea := image.Rectangle{camera.getCellArea(visible.Min.X, visible.Min.Y).Min,
+		camera.getCellArea(visible.Max.X, visible.Max.Y).Min.Add(thickness)}.Intersect(camera.frame)
+	for x := visible.Min.X; x <= visible.Max.X; x++ {
+		line := camera.getCellArea(x, visible.Min.Y).Min.X
+		fillArea(frame, image.Rect(line, gridArea.Min.Y, line+style.gridThickness, gridArea.Max.Y).Intersect(gridArea),
+			style.gridIndex)
+	}
+	for y := visible.Min.Y; y <= visible.Max.Y; y++ {
+		line := camera.getCellArea(visible.Min.X, y).Min.Y
+		fillArea(frame, image.Rect(gridArea.Min.X, line, gridArea.Max.X, line+style.gridThickness).Intersect(gridArea),
+			style.gridIndex)
+	}
+}
+
+func (style *cellStyle) hasGrid(camera *lifeCamera) bool {
+	return style.grid && camera.scale > float64(style.gridThickness)
+}
+
+func (style *cellStyle) getCellArea(camera *lifeCamera, x, y int) image.Rectangle {
+	area := camera.getCellArea(x, y)
+	if style.hasGrid(camera) {
+		area.Min = area.Min.Add(image.Pt(style.gridThickness, style.gridThickness))
+	}
+	return area
+}
+
+func (caption *frameCaption) getLines(generation int, universe lifeUniverse) []string {
+	var lines []string
+	if len(caption.text) > 0 {
+		lines = append(lines, caption.text)
+	}
+	if caption.generation {
+		lines = append(lines, fmt.Sprintf("generation %d", generation))
+	}
+	if caption.population {
+		lines = append(lines, fmt.Sprintf("population %d", getPopulation(universe)))
+	}
+	return lines
+}
+
+func drawCaption(frame *image.Paletted, caption *frameCaption, area image.Rectangle, generation int,
+	universe lifeUniverse) {
+	lines := caption.getLines(generation, universe)
+	if len(lines) == 0 {
+		return
+	}
+	var size image.Point
+	for _, line := range lines {
+		if width := getTextWidth(line, caption.scale); width > size.X {
+			size.X = width
+		}
+	}
+	lineHeight := 8 * caption.scale
+	size.Y = len(lines)*lineHeight - caption.scale
+	at := gAvailTextCorners[caption.corner](area.Inset(2*caption.scale), size)
+	for l, line := range lines {
+		lineAt := at.Add(image.Pt(0, l*lineHeight))
+		if strings.HasSuffix(caption.corner, "right") {
+			lineAt.X += size.X - getTextWidth(line, caption.scale)
+		}
+		drawText(frame, line, lineAt, caption.scale, caption.colorIndex)
+	}
+}
+
+func getTextWidth(text string, scale int) int {
+	if len(text) == 0 {
+		return 0
+	}
+	return (6*len([]rune(text)) - 1) * scale
+}
+
+func drawText(img *image.Paletted, text string, at image.Point, scale int, colorIndex uint8) {
+	for _, r := range text {
+		if r < ' ' || int(r-' ') >= len(gBitmapFont) {
+			r = '?'
+		}
+		for x, column := range gBitmapFont[r-' '] {
+			for y := 0; y < 7; y++ {
+				if column&(1<<uint(y)) != 0 {
+					dot := image.Rect(at.X+x*scale, at.Y+y*scale, at.X+(x+1)*scale, at.Y+(y+1)*scale)
+					fillArea(img, dot, colorIndex)
+				}
+			}
+		}
+		at.X += 6 * scale
+	}
+}
+
+func isInSquare(x, y, width, height int) bool {
+	return true
+}
+
+func isInCircle(x, y, width, height int) bool {
+	dx, dy := 2*x+1-width, 2*y+1-height
+	return dx*dx*height*height+dy*dy*width*width <= width*width*height*height
+}
+
+func isInDiamond(x, y, width, height int) bool {
+	dx, dy := 2*x+1-width, 2*y+1-height
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx*height+dy*width <= width*height
+}
+
+func isInRoundedSquare(x, y, width, height int) bool {
+	radius := width
+	if height < radius {
+		radius = height
+	}
+	radius /= 4
+	distance := func(c, size int) int {
+		if c < radius {
+			return radius - c
+		} else if c > size-1-radius {
+			return c - (size - 1 - radius)
+		}
+		return 0
+	}
+	dx, dy := distance(x, width), distance(y, height)
+	return dx*dx+dy*dy <= radius*radius
+}
+
+func (colorizer *cellColorizer) addColor(cl color.Color) uint8 {
+	colorizer.palette = append(colorizer.palette, cl)
+	return uint8(len(colorizer.palette) - 1)
+}
+
+func newLifeCamera(viewport image.Rectangle, frameWidth, frameHeight, cellSizeInPixels int,
+	fit, follow bool) *lifeCamera {
+	camera := &lifeCamera{centerX: float64(viewport.Min.X+viewport.Max.X) / 2,
+		centerY: float64(viewport.Min.Y+viewport.Max.Y) / 2,
+		width:   float64(viewport.Dx()), height: float64(viewport.Dy()),
+		scale: float64(cellSizeInPixels), frame: image.Rect(0, 0, frameWidth, frameHeight),
+		fit: fit, follow: follow}
+	if fit {
+		camera.scale = math.Min(float64(frameWidth)/camera.width, float64(frameHeight)/camera.height)
+	}
+	return camera
+}
+
+func (camera *lifeCamera) track(boundingBox image.Rectangle, snap bool) {
+	if !camera.follow || boundingBox.Empty() {
+		return
+	}
+	boxWidth, boxHeight := float64(boundingBox.Dx()), float64(boundingBox.Dy())
+	if camera.fit && (boxWidth > camera.width || boxHeight > camera.height) {
+		zoom := math.Max(boxWidth/camera.width, boxHeight/camera.height)
+		camera.width *= zoom
+		camera.height *= zoom
+		camera.scale /= zoom
+	}
+	targetX := float64(boundingBox.Min.X+boundingBox.Max.X) / 2
+	targetY := float64(boundingBox.Min.Y+boundingBox.Max.Y) / 2
+	if snap {
+		camera.centerX, camera.centerY = targetX, targetY
+		return
+	}
+	camera.centerX = keepInSight(camera.centerX+(targetX-camera.centerX)*gCameraEasing, camera.width,
+		boundingBox.Min.X, boundingBox.Max.X)
+	camera.centerY = keepInSight(camera.centerY+(targetY-camera.centerY)*gCameraEasing, camera.height,
+		boundingBox.Min.Y, boundingBox.Max.Y)
+}
+
+func keepInSight(center, size float64, min, max int) float64 {
+	if float64(max-min) > size {
+		return center
+	}
+	return math.Max(math.Min(center, float64(min)+size/2), float64(max)-size/2)
+}
+
+func (camera *lifeCamera) getOrigin() (float64, float64) {
+	return camera.centerX - camera.width/2, camera.centerY - camera.height/2
+}
+
+func (camera *lifeCamera) getVisibleArea() image.Rectangle {
+	x, y := camera.getOrigin()
+	return image.Rect(int(math.Floor(x)), int(math.Floor(y)),
+		int(math.Ceil(x+camera.width)), int(math.Ceil(y+camera.height)))
+}
+
+func (camera *lifeCamera) getCellArea(x, y int) image.Rectangle {
+	originX, originY := camera.getOrigin()
+	offsetX, offsetY := 0, 0
+	if camera.fit {
+		offsetX = int(math.Floor((float64(camera.frame.Dx()) - camera.width*camera.scale) / 2))
+		offsetY = int(math.Floor((float64(camera.frame.Dy()) - camera.height*camera.scale) / 2))
+	}
+	toPixel := func(cell int, origin float64, offset int) int {
+		return int(math.Floor((float64(cell)-origin)*camera.scale)) + offset
+	}
+	area := image.Rect(toPixel(x, originX, offsetX), toPixel(y, originY, offsetY),
+		toPixel(x+1, originX, offsetX), toPixel(y+1, originY, offsetY))
+	if area.Dx() == 0 {
+		area.Max.X++
+	}
+	if area.Dy() == 0 {
+		area.Max.Y++
+	}
+	return area
+}
+
+func getOutput() io.Writer {
+	out := getOption("out", "")
+	if len(out) == 0 {
+		return os.Stdout
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v.\n", err)
+		os.Exit(1)
+	}
+	return f
+}
+
+func makeGIFofLife(out io.Writer,
+	colorizer *cellColorizer,
+	width, height,
+	delay int,
+	endless bool,
+	camera *lifeCamera, style *cellStyle, caption *frameCaption,
+	universe lifeUniverse, generationNr, generationStep int,
+	stopOnCycle bool, stats *lifeStatsTracker, stripHeight int,
+	optimize, reportSavings bool) (*lifeCycle, *gifSavings, error) {
+	var cycle *lifeCycle
+	var populations []int
+	var history *lifeHistory
+	if stopOnCycle || stripHeight > 0 {
+		cycle, populations = surveyUniverse(universe.clone(), generationNr, generationStep, stopOnCycle)
+	} else {
+		history = newLifeHistory(gMaxCycleFrames)
+	}
+	firstGeneration, lastGeneration := 0, generationNr
+	if stopOnCycle && cycle != nil {
+		firstGeneration, lastGeneration = cycle.first, cycle.first+cycle.period
+	}
+	loopCount := 1
+	if endless {
+		loopCount = 0
+	}
+	if (lastGeneration-firstGeneration+generationStep-1)/generationStep < 2 {
+		loopCount = -1
+	}
+	stream := newGIFStream(out, width, height+stripHeight, colorizer.palette, loopCount, optimize, reportSavings)
+	var strip *image.Paletted
+	if stripHeight > 0 {
+		strip = makePopulationStrip(colorizer.palette, populations, image.Rect(0, height, width, height+stripHeight))
+	}
+	var ages *cellAgeTracker
+	if colorizer.tracksAge {
+		ages = newCellAgeTracker()
+	}
+	frame := image.NewPaletted(image.Rect(0, 0, width, height+stripHeight), colorizer.palette)
+	for g := 0; g < generationNr && stream.err == nil; g += generationStep {
+		if stats != nil {
+			stats.record(universe, g)
+		}
+		if g == lastGeneration {
+			break
+		}
+		if history != nil {
+			if cycle = history.findCycle(universe, g); cycle != nil {
+				history = nil
+			}
+		}
+		camera.track(universe.getBoundingBox(), g == 0)
+		if ages != nil {
+			ages.update(universe, colorizer.trailLength)
+		}
+		if g < firstGeneration {
+			universe.nextGenerations(generationStep)
+			continue
+		}
+		viewport := camera.getVisibleArea()
+		for p := range frame.Pix {
+			frame.Pix[p] = 0
+		}
+		if ages != nil {
+			var ghosts []cellCoord
+			for cell := range ages.deadFor {
+				if (image.Point{cell.x, cell.y}).In(viewport) {
+					ghosts = append(ghosts, cell)
+				}
+			}
+			// Cells overlap when scaled below one pixel, thus the order must not depend on maps.
+			sortCells(ghosts)
+			for _, cell := range ghosts {
+				drawAliveCell(frame, style.getCellArea(camera, cell.x, cell.y), style,
+					int(colorizer.ghostIndex(ages.deadFor[cell])))
+			}
+			var alive []cellCoord
+			universe.forEachAlive(viewport, func(x, y int) {
+				alive = append(alive, cellCoord{x, y})
+			})
+			sortCells(alive)
+			for _, cell := range alive {
+				drawAliveCell(frame, style.getCellArea(camera, cell.x, cell.y), style,
+					int(colorizer.aliveIndex(ages.ages[cell])))
+			}
+		} else {
+			universe.forEachAlive(viewport, func(x, y int) {
+				drawAliveCell(frame, style.getCellArea(camera, x, y), style, 1)
+			})
+		}
+		drawGrid(frame, camera, style)
+		if caption != nil {
+			drawCaption(frame, caption, camera.frame, g, universe)
+		}
+		if strip != nil {
+			drawPopulationStrip(frame, strip, (g-firstGeneration)/generationStep, len(populations))
+		}
+		stream.writeFrame(frame, delay)
+		universe.nextGenerations(generationStep)
+	}
+	savings, err := stream.close()
+	return cycle, savings, err
+}
+
+func surveyUniverse(universe lifeUniverse, generationNr, generationStep int,
+	stopOnCycle bool) (*lifeCycle, []int) {
+	var cycle *lifeCycle
+	var populations []int
+	history := newLifeHistory(gMaxCycleFrames)
+	for g := 0; g < generationNr; g += generationStep {
+		if cycle == nil {
+			if cycle = history.findCycle(universe, g); cycle != nil && stopOnCycle {
+				return cycle, populations[cycle.first/generationStep:]
+			}
+		}
+		populations = append(populations, getPopulation(universe))
+		universe.nextGenerations(generationStep)
+	}
+	return cycle, populations
+}
+
+func newLifeHistory(size int) *lifeHistory {
+	return &lifeHistory{states: make(map[lifeStateKey]lifeState), size: size}
+}
+
+func (history *lifeHistory) findCycle(universe lifeUniverse, generation int) *lifeCycle {
+	key, state := getUniverseState(universe, generation)
+	if first, seen := history.states[key]; seen {
+		return &lifeCycle{first: first.generation, period: generation - first.generation,
+			displacement: state.origin.Sub(first.origin), population: key.population}
+	}
+	if len(history.keys) < history.size {
+		history.keys = append(history.keys, key)
+	} else {
+		delete(history.states, history.keys[history.next])
+		history.keys[history.next] = key
+		history.next = (history.next + 1) % len(history.keys)
+	}
+	history.states[key] = state
+	return nil
+}
+
+func newGIFStream(out io.Writer, width, height int, palette color.Palette, loopCount int,
+	optimize, reportSavings bool) *gifStream {
+	stream := &gifStream{optimize: optimize, reportSavings: optimize && reportSavings}
+	stream.flusher, _ = out.(http.Flusher)
+	stream.out = bufio.NewWriter(io.MultiWriter(out, &stream.size))
+	if stream.reportSavings {
+		stream.fullLitWidth = getLZWLiteralWidth(len(palette))
+		writeGIFHeader(&stream.fullSize, width, height, palette, loopCount)
+	}
+	if optimize {
+		palette = append(append(color.Palette{}, palette...), color.Transparent)
+		stream.transparentIndex = uint8(len(palette) - 1)
+	}
+	stream.litWidth = getLZWLiteralWidth(len(palette))
+	stream.err = writeGIFHeader(stream.out, width, height, palette, loopCount)
+	return stream
+}
+
+func (stream *gifStream) writeFrame(frame *image.Paletted, delay int) {
+	if stream.err != nil {
+		return
+	}
+	if !stream.optimize {
+		if stream.err = writeGIFImage(stream.out, frame, delay, 0, -1, stream.litWidth); stream.err == nil {
+			stream.flush()
+		}
+		return
+	}
+	if stream.reportSavings {
+		writeGIFImage(&stream.fullSize, frame, delay, 0, -1, stream.fullLitWidth)
+	}
+	if stream.previous == nil {
+		stream.previous = image.NewPaletted(frame.Rect, frame.Palette)
+		copy(stream.previous.Pix, frame.Pix)
+		stream.pending = image.NewPaletted(frame.Rect, frame.Palette)
+		copy(stream.pending.Pix, frame.Pix)
+		stream.pendingDelay = delay
+		return
+	}
+	changedArea := getChangedArea(stream.previous, frame)
+	if changedArea.Empty() && stream.pendingDelay+delay <= gMaxGIFDelay {
+		stream.pendingDelay += delay
+		stream.mergedFrames++
+		return
+	} else if changedArea.Empty() {
+		// The delay does not fit in a GIF anymore, a single transparent pixel goes on.
+		changedArea = image.Rect(0, 0, 1, 1)
+	}
+	stream.writePending()
+	delta := image.NewPaletted(changedArea, frame.Palette)
+	for y := changedArea.Min.Y; y < changedArea.Max.Y; y++ {
+		for x := changedArea.Min.X; x < changedArea.Max.X; x++ {
+			if colorIndex := frame.ColorIndexAt(x, y); colorIndex != stream.previous.ColorIndexAt(x, y) {
+				delta.SetColorIndex(x, y, colorIndex)
+			} else {
+				delta.SetColorIndex(x, y, stream.transparentIndex)
+			}
+		}
+	}
+	copy(stream.previous.Pix, frame.Pix)
+	stream.pending, stream.pendingDelay = delta, delay
+}
+
+func (stream *gifStream) writePending() {
+	if stream.pending == nil || stream.err != nil {
+		return
+	}
+	stream.err = writeGIFImage(stream.out, stream.pending, stream.pendingDelay, gif.DisposalNone,
+		int(stream.transparentIndex), stream.litWidth)
+	stream.pending = nil
+	if stream.err == nil {
+		stream.flush()
+	}
+}
+
+func (stream *gifStream) flush() {
+	if stream.err = stream.out.Flush(); stream.err == nil && stream.flusher != nil {
+		stream.flusher.Flush()
+	}
+}
+
+func (stream *gifStream) close() (*gifSavings, error) {
+	stream.writePending()
+	if stream.err == nil {
+		if stream.err = stream.out.WriteByte(0x3B); stream.err == nil {
+			stream.flush()
+		}
+	}
+	if stream.err != nil {
+		return nil, stream.err
+	}
+	if !stream.reportSavings {
+		return nil, nil
+	}
+	stream.fullSize++
+	return &gifSavings{fullSize: int(stream.fullSize), size: int(stream.size), mergedFrames: stream.mergedFrames}, nil
+}
+
+func writeGIFHeader(out io.Writer, width, height int, palette color.Palette, loopCount int) error {
+	sizeBits := getLZWLiteralWidth(len(palette)) - 1
+	if len(palette) <= 2 {
+		sizeBits = 0
+	}
+	header := []byte("GIF89a")
+	header = append(header, byte(width), byte(width>>8), byte(height), byte(height>>8), 0x80|byte(sizeBits), 0, 0)
+	for c := 0; c < 2<<uint(sizeBits); c++ {
+		var r, g, b uint32
+		if c < len(palette) {
+			r, g, b, _ = palette[c].RGBA()
+		}
+		header = append(header, byte(r>>8), byte(g>>8), byte(b>>8))
+	}
+	if loopCount >= 0 {
+		header = append(header, 0x21, 0xFF, 0x0B)
+		header = append(header, "NETSCAPE2.0"...)
+		header = append(header, 0x03, 0x01, byte(loopCount), byte(loopCount>>8), 0x00)
+	}
+	_, err := out.Write(header)
+	return err
+}
+
+func writeGIFImage(out io.Writer, img *image.Paletted, delay int, disposal byte, transparentIndex,
+	litWidth int) error {
+	area := img.Rect
+	block := []byte{0x21, 0xF9, 0x04, disposal << 2, byte(delay), byte(delay >> 8), 0x00, 0x00}
+	if transparentIndex >= 0 {
+		block[3] |= 0x01
+		block[6] = byte(transparentIndex)
+	}
+	block = append(block, 0x2C, byte(area.Min.X), byte(area.Min.X>>8), byte(area.Min.Y), byte(area.Min.Y>>8),
+		byte(area.Dx()), byte(area.Dx()>>8), byte(area.Dy()), byte(area.Dy()>>8), 0x00, byte(litWidth))
+	if _, err := out.Write(block); err != nil {
+		return err
+	}
+	subBlocks := &gifSubBlockWriter{out: out}
+	compressor := lzw.NewWriter(subBlocks, lzw.LSB, litWidth)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		if _, err := compressor.Write(img.Pix[img.PixOffset(area.Min.X, y):img.PixOffset(area.Max.X, y)]); err != nil {
+			compressor.Close()
+			return err
+		}
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	return subBlocks.close()
+}
+
+func getLZWLiteralWidth(colorsNr int) int {
+	litWidth := bits.Len(uint(colorsNr - 1))
+	if litWidth < 2 {
+		litWidth = 2
+	}
+	return litWidth
+}
+
+func (writer *gifSubBlockWriter) Write(data []byte) (int, error) {
+	for written := 0; written < len(data); {
+		n := copy(writer.block[1+writer.size:], data[written:])
+		writer.size += n
+		written += n
+		if writer.size == 255 {
+			writer.block[0] = 255
+			if _, err := writer.out.Write(writer.block[:]); err != nil {
+				return written, err
+			}
+			writer.size = 0
+		}
+	}
+	return len(data), nil
+}
+
+func (writer *gifSubBlockWriter) close() error {
+	if writer.size > 0 {
+		writer.block[0] = byte(writer.size)
+		if _, err := writer.out.Write(writer.block[:writer.size+1]); err != nil {
+			return err
+		}
+	}
+	_, err := writer.out.Write([]byte{0x00})
+	return err
+}
+
+func getChangedArea(previous, frame *image.Paletted) image.Rectangle {
+	var changedArea image.Rectangle
+	for y := frame.Rect.Min.Y; y < frame.Rect.Max.Y; y++ {
+		row := frame.Pix[frame.PixOffset(frame.Rect.Min.X, y):frame.PixOffset(frame.Rect.Max.X, y)]
+		previousRow := previous.Pix[previous.PixOffset(frame.Rect.Min.X, y):previous.PixOffset(frame.Rect.Max.X, y)]
+		if bytes.Equal(row, previousRow) {
+			continue
+		}
+		for x := range row {
+			if row[x] != previousRow[x] {
+				changedArea = changedArea.Union(image.Rect(frame.Rect.Min.X+x, y, frame.Rect.Min.X+x+1, y+1))
+			}
+		}
+	}
+	return changedArea
+}
+
+func (counter *byteCounter) Write(data []byte) (int, error) {
+	*counter += byteCounter(len(data))
+	return len(data), nil
+}
+
+func (savings *gifSavings) String() string {
+	saved := savings.fullSize - savings.size
+	return fmt.Sprintf("the frame-difference optimization saved %d of %d bytes (%.1f%%) merging %d identical frames",
+		saved, savings.fullSize, 100*float64(saved)/float64(savings.fullSize), savings.mergedFrames)
+}
+
+func newCellAgeTracker() *cellAgeTracker {
+	return &cellAgeTracker{ages: make(map[cellCoord]int), deadFor: make(map[cellCoord]int)}
+}
+
+func (tracker *cellAgeTracker) update(universe lifeUniverse, trailLength int) {
+	ages := make(map[cellCoord]int, len(tracker.ages))
+	universe.forEachAlive(universe.getBoundingBox(), func(x, y int) {
+		cell := cellCoord{x, y}
+		ages[cell] = tracker.ages[cell] + 1
+	})
+	for cell, deadFor := range tracker.deadFor {
+		if _, isAlive := ages[cell]; isAlive || deadFor >= trailLength {
+			delete(tracker.deadFor, cell)
+		} else {
+			tracker.deadFor[cell] = deadFor + 1
+		}
+	}
+	if trailLength > 0 {
+		for cell := range tracker.ages {
+			if _, isAlive := ages[cell]; !isAlive {
+				tracker.deadFor[cell] = 1
+			}
+		}
+	}
+	tracker.ages = ages
+}
+
+func makeBinaryColorizer(bkColor, fgColor, agedColor color.Color, stops []color.Color, trailLength int) *cellColorizer {
+	return &cellColorizer{palette: []color.Color{bkColor, fgColor}}
+}
+
+func makeAgeColorizer(bkColor, fgColor, agedColor color.Color, stops []color.Color, trailLength int) *cellColorizer {
+	colorizer := &cellColorizer{palette: []color.Color{bkColor}, tracksAge: true}
+	if stops == nil {
+		stops = []color.Color{fgColor, agedColor}
+	}
+	colorizer.palette = append(colorizer.palette, makeGradient(gAgeGradientLength, stops...)...)
+	colorizer.aliveIndex = getGradientIndexer(1, gAgeGradientLength)
+	return colorizer
+}
+
+func makeTrailColorizer(bkColor, fgColor, agedColor color.Color, stops []color.Color, trailLength int) *cellColorizer {
+	colorizer := &cellColorizer{palette: []color.Color{bkColor, fgColor}, tracksAge: true, trailLength: trailLength}
+	ghosts := makeGradient(trailLength+2, fgColor, bkColor)
+	colorizer.palette = append(colorizer.palette, ghosts[1:trailLength+1]...)
+	colorizer.aliveIndex = func(age int) uint8 { return 1 }
+	colorizer.ghostIndex = getGradientIndexer(2, trailLength)
+	return colorizer
+}
+
+func makeHeatColorizer(bkColor, fgColor, agedColor color.Color, stops []color.Color, trailLength int) *cellColorizer {
+	colorizer := &cellColorizer{palette: []color.Color{bkColor, fgColor}, tracksAge: true, trailLength: trailLength}
+	var ghostColor color.Color = color.RGBA{0x80, 0x00, 0x00, 0xFF}
+	if stops == nil {
+		white, yellow, red := color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, color.RGBA{0xFF, 0xFF, 0x00, 0xFF}, color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+		colorizer.palette = append(colorizer.palette, makeGradient(gAgeGradientLength/4, white, yellow)...)
+		colorizer.palette = append(colorizer.palette, makeGradient(gAgeGradientLength-gAgeGradientLength/4, yellow, red)...)
+	} else {
+		colorizer.palette = append(colorizer.palette, makeGradient(gAgeGradientLength, stops...)...)
+		ghostColor = makeGradient(3, color.Black, stops[len(stops)-1])[1]
+	}
+	ghosts := makeGradient(trailLength+1, ghostColor, bkColor)
+	colorizer.palette = append(colorizer.palette, ghosts[:trailLength]...)
+	colorizer.aliveIndex = getGradientIndexer(2, gAgeGradientLength)
+	colorizer.ghostIndex = getGradientIndexer(2+gAgeGradientLength, trailLength)
+	return colorizer
+}
+
+func getGradientIndexer(first, length int) func(value int) uint8 {
+	return func(value int) uint8 {
+		if value > length {
+			value = length
+		} else if value < 1 {
+			value = 1
+		}
+		return uint8(first + value - 1)
+	}
+}
+
+func makeGradient(length int, stops ...color.Color) []color.Color {
+	gradient := make([]color.Color, length)
+	for c := range gradient {
+		from, to, weight, weightTotal := stops[0], stops[0], 0, 1
+		if length > 1 && len(stops) > 1 {
+			position := c * (len(stops) - 1)
+			stop := position / (length - 1)
+			if stop == len(stops)-1 {
+				stop--
+			}
+			from, to = stops[stop], stops[stop+1]
+			weight, weightTotal = position-stop*(length-1), length-1
+		}
+		r0, g0, b0, _ := from.RGBA()
+		r1, g1, b1, _ := to.RGBA()
+		step := func(c0, c1 uint32) uint8 {
+			return uint8((int(c0>>8)*(weightTotal-weight) + int(c1>>8)*weight) / weightTotal)
+		}
+		gradient[c] = color.RGBA{step(r0, r1), step(g0, g1), step(b0, b1), 0xFF}
+	}
+	return gradient
+}
+
+func getPopulation(universe lifeUniverse) int {
+	var population int
+	universe.forEachAlive(universe.getBoundingBox(), func(x, y int) {
+		population++
+	})
+	return population
+}
+
+func makePopulationStrip(palette color.Palette, populations []int, stripArea image.Rectangle) *image.Paletted {
+	strip := image.NewPaletted(stripArea, palette)
+	drawLine(strip, image.Pt(stripArea.Min.X, stripArea.Min.Y), image.Pt(stripArea.Max.X-1, stripArea.Min.Y), 1)
+	drawPopulationCurve(strip, getPopulationCurveArea(stripArea), populations, 1)
+	return strip
+}
+
+func drawPopulationStrip(frame, strip *image.Paletted, frameNr, framesNr int) {
+	stripArea := strip.Rect
+	for y := stripArea.Min.Y; y < stripArea.Max.Y; y++ {
+		copy(frame.Pix[frame.PixOffset(stripArea.Min.X, y):frame.PixOffset(stripArea.Max.X, y)],
+			strip.Pix[strip.PixOffset(stripArea.Min.X, y):strip.PixOffset(stripArea.Max.X, y)])
+	}
+	curveArea := getPopulationCurveArea(stripArea)
+	cursor := getChartPoint(curveArea, frameNr, framesNr, 0, 0)
+	for y := curveArea.Min.Y; y < curveArea.Max.Y; y += 2 {
+		frame.SetColorIndex(cursor.X, y, 1)
+	}
+}
+
+func getPopulationCurveArea(stripArea image.Rectangle) image.Rectangle {
+	return image.Rect(stripArea.Min.X+1, stripArea.Min.Y+2, stripArea.Max.X-1, stripArea.Max.Y-1)
+}
+
+func drawPopulationCurve(img *image.Paletted, area image.Rectangle, populations []int, colorIndex uint8) {
+	var maxPopulation int
+	for _, population := range populations {
+		if population > maxPopulation {
+			maxPopulation = population
+		}
+	}
+	for p := range populations {
+		point := getChartPoint(area, p, len(populations), populations[p], maxPopulation)
+		if p == 0 {
+			img.SetColorIndex(point.X, point.Y, colorIndex)
+		} else {
+			drawLine(img, getChartPoint(area, p-1, len(populations), populations[p-1], maxPopulation), point, colorIndex)
+		}
+	}
+}
+
+func getChartPoint(area image.Rectangle, index, indexesNr, population, maxPopulation int) image.Point {
+	point := image.Pt(area.Min.X, area.Max.Y-1)
+	if indexesNr > 1 {
+		point.X += index * (area.Dx() - 1) / (indexesNr - 1)
+	}
+	if maxPopulation > 0 {
+		point.Y -= population * (area.Dy() - 1) / maxPopulation
+	}
+	return point
+}
+
+func drawLine(img *image.Paletted, from, to image.Point, colorIndex uint8) {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	xStep, yStep := 1, 1
+	if dx < 0 {
+		dx, xStep = -dx, -1
+	}
+	if dy > 0 {
+		dy = -dy
+	} else {
+		yStep = -1
+	}
+	err := dx + dy
+	for {
+		img.SetColorIndex(from.X, from.Y, colorIndex)
+		if from == to {
+			break
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			from.X += xStep
+		}
+		if e2 <= dx {
+			err += dx
+			from.Y += yStep
+		}
+	}
+}
+
+func writePopulationChart(filePath string, records []lifeStats, bkColor, fgColor color.Color) error {
+	writeChart, ok := gAvailChartFormats[strings.ToLower(filepath.Ext(filePath))]
+	if !ok {
+		return fmt.Errorf("the chart file must end with '.png' or '.svg'")
+	}
+	chartFile, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer chartFile.Close()
+	return writeChart(chartFile, records, bkColor, fgColor)
+}
+
+func writePopulationChartAsPNG(out io.Writer, records []lifeStats, bkColor, fgColor color.Color) error {
+	chart := image.NewPaletted(image.Rect(0, 0, gPopulationChartWidth, gPopulationChartHeight),
+		[]color.Color{bkColor, fgColor})
+	curveArea := image.Rect(10, 10, gPopulationChartWidth-10, gPopulationChartHeight-10)
+	drawLine(chart, image.Pt(curveArea.Min.X-1, curveArea.Min.Y), image.Pt(curveArea.Min.X-1, curveArea.Max.Y), 1)
+	drawLine(chart, image.Pt(curveArea.Min.X-1, curveArea.Max.Y), image.Pt(curveArea.Max.X, curveArea.Max.Y), 1)
+	populations := make([]int, len(records))
+	for r, stats := range records {
+		populations[r] = stats.Population
+	}
+	drawPopulationCurve(chart, curveArea, populations, 1)
+	return png.Encode(out, chart)
+}
+
+func writePopulationChartAsSVG(out io.Writer, records []lifeStats, bkColor, fgColor color.Color) error {
+	curveArea := image.Rect(60, 10, gPopulationChartWidth-10, gPopulationChartHeight-30)
+	var maxPopulation, lastGeneration int
+	for _, stats := range records {
+		if stats.Population > maxPopulation {
+			maxPopulation = stats.Population
+		}
+		lastGeneration = stats.Generation
+	}
+	var points string
+	for r, stats := range records {
+		point := getChartPoint(curveArea, r, len(records), stats.Population, maxPopulation)
+		points += fmt.Sprintf("%d,%d ", point.X, point.Y)
+	}
+	bk, fg := getHexColor(bkColor), getHexColor(fgColor)
+	_, err := fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n"+
+		"<rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n"+
+		"<polyline points=\"%d,%d %d,%d %d,%d\" fill=\"none\" stroke=\"%s\"/>\n"+
+		"<polyline points=\"%s\" fill=\"none\" stroke=\"%s\"/>\n"+
+		"<text x=\"%d\" y=\"%d\" fill=\"%s\" font-size=\"12\" text-anchor=\"end\">%d</text>\n"+
+		"<text x=\"%d\" y=\"%d\" fill=\"%s\" font-size=\"12\" text-anchor=\"end\">0</text>\n"+
+		"<text x=\"%d\" y=\"%d\" fill=\"%s\" font-size=\"12\" text-anchor=\"end\">%d</text>\n"+
+		"<text x=\"%d\" y=\"%d\" fill=\"%s\" font-size=\"12\" text-anchor=\"middle\">generation</text>\n"+
+		"</svg>\n", gPopulationChartWidth, gPopulationChartHeight, bk,
+		curveArea.Min.X-1, curveArea.Min.Y, curveArea.Min.X-1, curveArea.Max.Y, curveArea.Max.X, curveArea.Max.Y, fg,
+		points, fg,
+		curveArea.Min.X-5, curveArea.Min.Y+10, fg, maxPopulation,
+		curveArea.Min.X-5, curveArea.Max.Y, fg,
+		curveArea.Max.X, curveArea.Max.Y+15, fg, lastGeneration,
+		(curveArea.Min.X+curveArea.Max.X)/2, curveArea.Max.Y+15, fg)
+	return err
+}
+
+func getHexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02X%02X%02X", r>>8, g>>8, b>>8)
+}
+
+// The state is hashed relative to the bounding box, thus a spaceship yields the same key wherever it is.
+func getUniverseState(universe lifeUniverse, generation int) (lifeStateKey, lifeState) {
+	boundingBox := universe.getBoundingBox()
+	var key lifeStateKey
+	universe.forEachAlive(boundingBox, func(x, y int) {
+		cell := uint64(uint32(x-boundingBox.Min.X))<<32 | uint64(uint32(y-boundingBox.Min.Y))
+		key.hashA += mixBits(cell)
+		key.hashB += mixBits(cell ^ 0x9E3779B97F4A7C15)
+		key.population++
+	})
+	return key, lifeState{generation, boundingBox.Min}
+}
+
+func mixBits(value uint64) uint64 {
+	value = (value ^ (value >> 30)) * 0xBF58476D1CE4E5B9
+	value = (value ^ (value >> 27)) * 0x94D049BB133111EB
+	return value ^ (value >> 31)
+}
+
+func newLifeStatsTracker() *lifeStatsTracker {
+	return &lifeStatsTracker{previous: make(map[cellCoord]struct{})}
+}
+
+func (tracker *lifeStatsTracker) record(universe lifeUniverse, generation int) {
+	boundingBox := universe.getBoundingBox()
+	stats := lifeStats{Generation: generation, BBoxX: boundingBox.Min.X, BBoxY: boundingBox.Min.Y,
+		BBoxWidth: boundingBox.Dx(), BBoxHeight: boundingBox.Dy()}
+	current := make(map[cellCoord]struct{})
+	universe.forEachAlive(boundingBox, func(x, y int) {
+		cell := cellCoord{x, y}
+		current[cell] = struct{}{}
+		if _, wasAlive := tracker.previous[cell]; !wasAlive && len(tracker.records) > 0 {
+			stats.Births++
+		}
+	})
+	stats.Population = len(current)
+	if len(tracker.records) > 0 {
+		stats.Deaths = len(tracker.previous) - (stats.Population - stats.Births)
+		if len(tracker.previous) > 0 {
+			stats.ChangeRate = float64(stats.Births+stats.Deaths) / float64(len(tracker.previous))
+		}
+	}
+	tracker.previous = current
+	tracker.records = append(tracker.records, stats)
+}
+
+func writeStatsAsCSV(out io.Writer, records []lifeStats) error {
+	_, err := fmt.Fprintf(out, "generation,population,births,deaths,bbox_x,bbox_y,bbox_width,bbox_height,change_rate\n")
+	for _, stats := range records {
+		if err != nil {
+			break
+		}
+		_, err = fmt.Fprintf(out, "%d,%d,%d,%d,%d,%d,%d,%d,%.6f\n", stats.Generation, stats.Population,
+			stats.Births, stats.Deaths, stats.BBoxX, stats.BBoxY, stats.BBoxWidth, stats.BBoxHeight, stats.ChangeRate)
+	}
+	return err
+}
+
+func writeStatsAsJSON(out io.Writer, records []lifeStats) error {
+	if records == nil {
+		records = []lifeStats{}
+	}
+	data, err := json.MarshalIndent(records, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+func writeCensusAsTable(out io.Writer, census lifeCensus) error {
+	rows := [][]string{{"apgcode", "name", "count"}}
+	for _, object := range census.Objects {
+		rows = append(rows, []string{object.Apgcode, object.Name, strconv.Itoa(object.Count)})
+	}
+	if _, err := fmt.Fprintf(out, "%d soups of %s under %s, %d of them unstable.\n\n", census.Soups, census.Soup,
+		census.Rule, census.UnstableSoups); err != nil {
+		return err
+	}
+	return writeTable(out, rows)
+}
+
+func writeCensusAsJSON(out io.Writer, census lifeCensus) error {
+	if census.Objects == nil {
+		census.Objects = []censusObject{}
+	}
+	data, err := json.MarshalIndent(census, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+func writeObjectsAsTable(out io.Writer, objects []lifeObject) error {
+	rows := [][]string{{"x", "y", "size", "population", "kind", "period", "velocity", "apgcode", "name"}}
+	for _, object := range objects {
+		period := "-"
+		if object.Period > 0 {
+			period = strconv.Itoa(object.Period)
+		}
+		velocity := object.Velocity
+		if len(velocity) == 0 {
+			velocity = "-"
+		}
+		rows = append(rows, []string{strconv.Itoa(object.X), strconv.Itoa(object.Y),
+			fmt.Sprintf("%dx%d", object.Width, object.Height), strconv.Itoa(object.Population), object.Kind, period,
+			velocity, object.Apgcode, object.Name})
+	}
+	return writeTable(out, rows)
+}
+
+func writeObjectsAsJSON(out io.Writer, objects []lifeObject) error {
+	if objects == nil {
+		objects = []lifeObject{}
+	}
+	data, err := json.MarshalIndent(objects, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+func writeTable(out io.Writer, rows [][]string) error {
+	var widths []int
+	for _, row := range rows {
+		for c, field := range row {
+			if c == len(widths) {
+				widths = append(widths, 0)
+			}
+			if len(field) > widths[c] {
+				widths[c] = len(field)
+			}
+		}
+	}
+	for _, row := range rows {
+		var line string
+		for c, field := range row {
+			line += fmt.Sprintf("%-*s  ", widths[c], field)
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getUniverseObjects(universe lifeUniverse, rule lifeRule, gap int) []lifeObject {
+	var cells lifePattern
+	universe.forEachAlive(universe.getBoundingBox(), func(x, y int) {
+		cells.cells = append(cells.cells, cellCoord{x, y})
+	})
+	var objects []lifeObject
+	for _, object := range splitIntoObjects(cells, rule, gap) {
+		objects = append(objects, classifyObject(object, rule))
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Y != objects[j].Y {
+			return objects[i].Y < objects[j].Y
+		}
+		return objects[i].X < objects[j].X
+	})
+	return objects
+}
+
+func getSoupAsh(soup lifePattern, rule lifeRule, maxGenerationNr int) (lifePattern, bool) {
+	universe, _ := makeSparseUniverse(0, 0, rule, planeTopology, 1)
+	setBigBangGeneration(universe, nil, soup)
+	window := 4 * gMaxObjectPeriod
+	var populations []int
+	for g := 0; g <= maxGenerationNr; g++ {
+		if populations = append(populations, getPopulation(universe)); len(populations) > window {
+			populations = populations[1:]
+		}
+		if g >= window && g%gMaxObjectPeriod == 0 && isPeriodic(populations) {
+			if ash := getPatternFromUniverse(universe, "", rule); isSettled(ash, rule) {
+				return ash, true
+			}
+		}
+		universe.nextGeneration()
+	}
+	return lifePattern{}, false
+}
+
+// The ash is settled once it repeats itself in place, the spaceships flying away aside.
+func isSettled(ash lifePattern, rule lifeRule) bool {
+	var debris lifePattern
+	for _, object := range getConnectedComponents(ash, gCensusGap+1) {
+		if classifyObject(object, rule).Kind != "spaceship" {
+			debris.cells = append(debris.cells, object.cells...)
+		}
+	}
+	universe, _ := makeSparseUniverse(0, 0, rule, planeTopology, 1)
+	setBigBangGeneration(universe, nil, debris)
+	history := newLifeHistory(gMaxObjectPeriod + 1)
+	for g := 0; g <= gMaxObjectPeriod; g++ {
+		if cycle := history.findCycle(universe, g); cycle != nil {
+			return cycle.first == 0 && cycle.displacement == image.ZP
+		}
+		universe.nextGeneration()
+	}
+	return false
+}
+
+func isPeriodic(values []int) bool {
+	for period := 1; period <= gMaxObjectPeriod && 2*period <= len(values); period++ {
+		v := period
+		for ; v < len(values) && values[v] == values[v-period]; v++ {
+		}
+		if v == len(values) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitIntoObjects(ash lifePattern, rule lifeRule, gap int) []lifePattern {
+	var objects []lifePattern
+	for _, object := range getConnectedComponents(ash, gap+1) {
+		if pieces := getConnectedComponents(object, 1); len(pieces) > 1 && evolveApart(object, pieces, rule) &&
+			arePeriodic(pieces, rule) {
+			objects = append(objects, pieces...)
+		} else {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+func arePeriodic(objects []lifePattern, rule lifeRule) bool {
+	for _, object := range objects {
+		if classifyObject(object, rule).Period == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func getConnectedComponents(pattern lifePattern, reach int) []lifePattern {
+	unvisited := make(map[cellCoord]bool, len(pattern.cells))
+	for _, cell := range pattern.cells {
+		unvisited[cell] = true
+	}
+	var components []lifePattern
+	for _, cell := range pattern.cells {
+		if !unvisited[cell] {
+			continue
+		}
+		delete(unvisited, cell)
+		component := lifePattern{cells: []cellCoord{cell}}
+		for c := 0; c < len(component.cells); c++ {
+			for dy := -reach; dy <= reach; dy++ {
+				for dx := -reach; dx <= reach; dx++ {
+					neighbour := cellCoord{component.cells[c].x + dx, component.cells[c].y + dy}
+					if unvisited[neighbour] {
+						delete(unvisited, neighbour)
+						component.cells = append(component.cells, neighbour)
+					}
+				}
+			}
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+func evolveApart(whole lifePattern, pieces []lifePattern, rule lifeRule) bool {
+	wholeUniverse, _ := makeSparseUniverse(0, 0, rule, planeTopology, 1)
+	setBigBangGeneration(wholeUniverse, nil, whole)
+	pieceUniverses := make([]lifeUniverse, len(pieces))
+	for p, piece := range pieces {
+		pieceUniverses[p], _ = makeSparseUniverse(0, 0, rule, planeTopology, 1)
+		setBigBangGeneration(pieceUniverses[p], nil, piece)
+	}
+	for g := 0; g < gMaxObjectPeriod; g++ {
+		wholeUniverse.nextGeneration()
+		cellsNr := 0
+		for _, pieceUniverse := range pieceUniverses {
+			pieceUniverse.nextGeneration()
+			apart := true
+			pieceUniverse.forEachAlive(pieceUniverse.getBoundingBox(), func(x, y int) {
+				apart = apart && wholeUniverse.isAlive(x, y)
+				cellsNr++
+			})
+			if !apart {
+				return false
+			}
+		}
+		if cellsNr != getPopulation(wholeUniverse) {
+			return false
+		}
+	}
+	return true
+}
+
+func classifyObject(object lifePattern, rule lifeRule) lifeObject {
+	universe, _ := makeSparseUniverse(0, 0, rule, planeTopology, 1)
+	setBigBangGeneration(universe, nil, object)
+	boundingBox := universe.getBoundingBox()
+	classified := lifeObject{X: boundingBox.Min.X, Y: boundingBox.Min.Y, Width: boundingBox.Dx(),
+		Height: boundingBox.Dy(), Population: len(object.cells), Kind: "unclassified", Apgcode: "unclassified"}
+	first := getPatternFromUniverse(universe, "", rule)
+	phases := []lifePattern{first}
+	for period := 1; period <= gMaxObjectPeriod; period++ {
+		universe.nextGeneration()
+		phase := getPatternFromUniverse(universe, "", rule)
+		if !isSamePattern(phase, first) {
+			phases = append(phases, phase)
+			continue
+		}
+		var prefix string
+		switch displacement := universe.getBoundingBox().Min.Sub(boundingBox.Min); {
+		case displacement != image.ZP:
+			classified.Kind, classified.Velocity = "spaceship", getVelocity(displacement, period)
+			prefix = fmt.Sprintf("xq%d", period)
+		case period > 1:
+			classified.Kind = "oscillator"
+			prefix = fmt.Sprintf("xp%d", period)
+		default:
+			classified.Kind = "still life"
+			prefix = fmt.Sprintf("xs%d", len(first.cells))
+		}
+		var wechsler string
+		for _, phase := range phases {
+			for _, orientation := range getOrientations(phase) {
+				code := getWechslerCode(orientation)
+				if len(wechsler) == 0 || len(code) < len(wechsler) || (len(code) == len(wechsler) && code < wechsler) {
+					wechsler = code
+				}
+			}
+		}
+		classified.Period, classified.Apgcode = period, prefix+"_"+wechsler
+		if rule.String() == gDefaultRule {
+			classified.Name = gKnownObjectNames[classified.Apgcode]
+		}
+		break
+	}
+	return classified
+}
+
+func getVelocity(displacement image.Point, period int) string {
+	dx, dy := displacement.X, displacement.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	distance := dx
+	if dy > distance {
+		distance = dy
+	}
+	divisor := distance
+	for d := period; d != 0; {
+		divisor, d = d, divisor%d
+	}
+	speed := fmt.Sprintf("%dc/%d", distance/divisor, period/divisor)
+	if distance == divisor {
+		speed = fmt.Sprintf("c/%d", period/divisor)
+	}
+	switch {
+	case dx == 0 || dy == 0:
+		return speed + " orthogonal"
+	case dx == dy:
+		return speed + " diagonal"
+	}
+	return speed + " oblique"
+}
+
+func isSamePattern(a, b lifePattern) bool {
+	if len(a.cells) != len(b.cells) {
+		return false
+	}
+	for c := range a.cells {
+		if a.cells[c] != b.cells[c] {
+			return false
+		}
+	}
+	return true
+}
+
+func getOrientations(pattern lifePattern) []lifePattern {
+	orientations := []lifePattern{pattern.normalize(), pattern.flipX()}
+	for o := 0; o < 6; o++ {
+		orientations = append(orientations, orientations[o].rotate(90))
+	}
+	return orientations
+}
+
+func transformPattern(pattern lifePattern, transform func(cell cellCoord, width, height int) cellCoord) lifePattern {
+	width, height := pattern.getSize()
+	transformed := lifePattern{name: pattern.name, rule: pattern.rule, cells: make([]cellCoord, len(pattern.cells))}
+	for c, cell := range pattern.cells {
+		transformed.cells[c] = transform(cell, width, height)
+	}
+	return transformed.normalize()
+}
+
+func getWechslerCode(pattern lifePattern) string {
+	const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	width, height := pattern.getSize()
+	stripes := make([][]int, (height+4)/5)
+	for s := range stripes {
+		stripes[s] = make([]int, width)
+	}
+	for _, cell := range pattern.cells {
+		stripes[cell.y/5][cell.x] |= 1 << uint(cell.y%5)
+	}
+	codes := make([]string, len(stripes))
+	for s, stripe := range stripes {
+		zerosNr := 0
+		for _, column := range stripe {
+			if column == 0 {
+				zerosNr++
+				continue
+			}
+			for zerosNr >= 4 {
+				runLength := zerosNr
+				if runLength > 39 {
+					runLength = 39
+				}
+				codes[s] += "y" + string(digits[runLength-4])
+				zerosNr -= runLength
+			}
+			codes[s] += []string{"", "0", "w", "x"}[zerosNr] + string(digits[column])
+			zerosNr = 0
+		}
+	}
+	return strings.Join(codes, "z")
+}
+
+func (cycle *lifeCycle) String() string {
+	switch {
+	case cycle.population == 0:
+		return fmt.Sprintf("the universe is extinct since generation %d", cycle.first)
+	case cycle.displacement != image.ZP:
+		return fmt.Sprintf("the universe repeats itself every %d generations translated by (%d,%d) since generation %d",
+			cycle.period, cycle.displacement.X, cycle.displacement.Y, cycle.first)
+	case cycle.period == 1:
+		return fmt.Sprintf("the universe is a still life since generation %d", cycle.first)
+	}
+	return fmt.Sprintf("the universe repeats itself every %d generations since generation %d", cycle.period, cycle.first)
+}
+
+func getViewport(viewport string, xNr, yNr int) (image.Rectangle, error) {
+	fields := strings.Split(viewport, ",")
+	if len(fields) == 4 {
+		var err error
+		if xNr, err = strconv.Atoi(strings.TrimSpace(fields[2])); err != nil || xNr <= 0 {
+			return image.Rectangle{}, fmt.Errorf("'%s' is not a valid width", fields[2])
+		}
+		if yNr, err = strconv.Atoi(strings.TrimSpace(fields[3])); err != nil || yNr <= 0 {
+			return image.Rectangle{}, fmt.Errorf("'%s' is not a valid height", fields[3])
+		}
+		viewport = fields[0] + "," + fields[1]
+	} else if len(fields) != 2 {
+		return image.Rectangle{}, fmt.Errorf("'%s' is not in form <x>,<y>[,<w>,<h>]", viewport)
+	}
+	at, err := parseCoords(viewport)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	return image.Rect(at.X, at.Y, at.X+xNr, at.Y+yNr), nil
+}
+
+func parseCoords(str string) (image.Point, error) {
+	coords := strings.Split(str, ",")
+	if len(coords) != 2 {
+		return image.Point{}, fmt.Errorf("'%s' is not in form <x>,<y>", str)
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(coords[0]))
+	if err != nil {
+		return image.Point{}, fmt.Errorf("'%s' is not a valid x coordinate", coords[0])
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(coords[1]))
+	if err != nil {
+		return image.Point{}, fmt.Errorf("'%s' is not a valid y coordinate", coords[1])
+	}
+	return image.Pt(x, y), nil
+}
+
+func makeGameBoard(xNr, yNr int) [][]byte {
+	var cells [][]byte
+	cells = make([][]byte, xNr)
+	for x := 0; x < xNr; x++ {
+		cells[x] = make([]byte, yNr)
+	}
+	return cells
+}
+
+func getCellCoords(str string) (int, int, bool) {
+	if strings.HasPrefix(str, "--") && strings.HasSuffix(str, ".") {
+		coords := strings.Split(str[2:len(str)-1], ",")
+		if len(coords) == 2 {
+			x, err := strconv.Atoi(coords[0])
+			if err == nil {
+				y, err := strconv.Atoi(coords[1])
+				return x, y, err == nil && isCellInRange(x, y)
+			}
+		}
+	}
+	return -1, -1, false
+}
+
+func isCellInRange(x, y int) bool {
+	return x > -gMaxCellCoord && x < gMaxCellCoord && y > -gMaxCellCoord && y < gMaxCellCoord
+}
+
+func setBigBangGeneration(universe lifeUniverse, args []string, patterns ...lifePattern) {
 	for _, a := range args {
-		x, y := getCellCoords(a)
-		if x > -1 && y > -1 && x < xNr && y < yNr {
-			cells[x][y] = 1
+		if x, y, ok := getCellCoords(a); ok {
+			universe.setAlive(x, y)
+		}
+	}
+	for _, pattern := range patterns {
+		for _, c := range pattern.cells {
+			universe.setAlive(c.x, c.y)
+		}
+	}
+}
+
+func makeSoup(soupSpec string, xNr, yNr int, random *rand.Rand) (lifePattern, error) {
+	var width, height int
+	var density float64
+	symmetryName := "C1"
+	spec := soupSpec
+	if colon := strings.Index(spec, ":"); colon > -1 {
+		spec, symmetryName = spec[:colon], strings.ToUpper(spec[colon+1:])
+	}
+	if _, err := fmt.Sscanf(strings.Replace(spec, "@", " ", 1), "%dx%d %g", &width, &height, &density); err != nil ||
+		width <= 0 || height <= 0 {
+		return lifePattern{}, fmt.Errorf("'%s' is not in form <w>x<h>@<density>[:<symmetry>]", soupSpec)
+	}
+	if width > xNr || height > yNr {
+		return lifePattern{}, fmt.Errorf("%dx%d does not fit the %dx%d board", width, height, xNr, yNr)
+	}
+	if density < 0 || density > 1 {
+		return lifePattern{}, fmt.Errorf("the density must be between 0 and 1")
+	}
+	symmetry, ok := gAvailSymmetries[symmetryName]
+	if !ok {
+		return lifePattern{}, fmt.Errorf("'%s' is not a known symmetry", symmetryName)
+	}
+	if (symmetryName == "C4" || symmetryName == "D8") && width != height {
+		return lifePattern{}, fmt.Errorf("the symmetry %s needs a square soup", symmetryName)
+	}
+	isAlive := make(map[cellCoord]bool)
+	var soup lifePattern
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			orbit := getOrbit(cellCoord{x, y}, width, height, symmetry)
+			if _, drawn := isAlive[orbit[0]]; !drawn {
+				alive := random.Float64() < density
+				for _, cell := range orbit {
+					isAlive[cell] = alive
+				}
+			}
+			if isAlive[cellCoord{x, y}] {
+				soup.cells = append(soup.cells, cellCoord{x, y})
+			}
+		}
+	}
+	return soup.translate((xNr-width)/2, (yNr-height)/2), nil
+}
+
+func rotateCell90(cell cellCoord, width, height int) cellCoord {
+	return cellCoord{width - 1 - cell.y, cell.x}
+}
+
+func rotateCell180(cell cellCoord, width, height int) cellCoord {
+	return cellCoord{width - 1 - cell.x, height - 1 - cell.y}
+}
+
+func mirrorCellX(cell cellCoord, width, height int) cellCoord {
+	return cellCoord{width - 1 - cell.x, cell.y}
+}
+
+func mirrorCellY(cell cellCoord, width, height int) cellCoord {
+	return cellCoord{cell.x, height - 1 - cell.y}
+}
+
+func getOrbit(cell cellCoord, width, height int, generators []func(cell cellCoord, width, height int) cellCoord) []cellCoord {
+	orbit := []cellCoord{cell}
+	inOrbit := map[cellCoord]bool{cell: true}
+	for c := 0; c < len(orbit); c++ {
+		for _, generator := range generators {
+			if image := generator(orbit[c], width, height); !inOrbit[image] {
+				inOrbit[image] = true
+				orbit = append(orbit, image)
+			}
+		}
+	}
+	return orbit
+}
+
+func placePatterns(placements []string, loaded lifePattern, loadedAt image.Point) (lifePattern, error) {
+	var placed []lifePattern
+	loadedPlaced := false
+	for _, placement := range placements {
+		pattern, isLoaded, err := placePattern(placement, loaded)
+		if err != nil {
+			return lifePattern{}, err
+		}
+		placed = append(placed, pattern)
+		loadedPlaced = loadedPlaced || isLoaded
+	}
+	if !loadedPlaced {
+		placed = append(placed, loaded.translate(loadedAt.X, loadedAt.Y))
+	}
+	return unitePatterns(placed...), nil
+}
+
+func placePattern(placement string, loaded lifePattern) (lifePattern, bool, error) {
+	transforms := strings.Split(placement, ":")
+	name, origin := transforms[0], gDefaultPatternAt
+	if at := strings.Index(name, "@"); at > -1 {
+		name, origin = name[:at], name[at+1:]
+	}
+	pattern := loaded
+	if name != "pattern" {
+		data, ok := gPatternLibrary[name]
+		if !ok {
+			return lifePattern{}, false, fmt.Errorf("'%s' is neither 'pattern' nor in the pattern library", name)
+		}
+		pattern, _ = parseRLEPattern(data, gMaxPatternSize, gMaxPatternSize)
+	}
+	patternAt, err := parseCoords(origin)
+	if err != nil {
+		return lifePattern{}, false, err
+	}
+	pattern = pattern.normalize()
+	for _, transform := range transforms[1:] {
+		switch transform {
+		case "0", "90", "180", "270":
+			degrees, _ := strconv.Atoi(transform)
+			pattern = pattern.rotate(degrees)
+		case "flip", "flip-x":
+			pattern = pattern.flipX()
+		case "flip-y":
+			pattern = pattern.flipY()
+		default:
+			return lifePattern{}, false, fmt.Errorf("'%s' is neither a rotation (0, 90, 180 or 270) nor "+
+				"a reflection (flip-x or flip-y)", transform)
+		}
+	}
+	return pattern.translate(patternAt.X, patternAt.Y), name == "pattern", nil
+}
+
+func loadPattern(filePath string, maxWidth, maxHeight int) (lifePattern, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return lifePattern{}, err
+	}
+	return parsePattern(string(data), maxWidth, maxHeight)
+}
+
+func parsePattern(data string, maxWidth, maxHeight int) (lifePattern, error) {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case len(line) == 0:
+			continue
+		case strings.HasPrefix(line, "#Life 1.06"):
+			return parseLife106Pattern(data)
+		case strings.HasPrefix(line, "#Life 1.05"):
+			return parseLife105Pattern(data)
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "!") || strings.Trim(line, ".O*") == "":
+			return parsePlainTextPattern(data)
+		case strings.HasPrefix(line, "x"):
+			return parseRLEPattern(data, maxWidth, maxHeight)
+		case strings.Trim(line, "-0123456789 \t") == "":
+			return parseLife106Pattern(data)
+		}
+		return lifePattern{}, fmt.Errorf("unknown pattern format")
+	}
+	return lifePattern{}, nil
+}
+
+func parsePlainTextPattern(data string) (lifePattern, error) {
+	var pattern lifePattern
+	y := 0
+	for l, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(line, "!") {
+			if strings.HasPrefix(line, "!Name:") {
+				pattern.name = strings.TrimSpace(line[6:])
+			} else if strings.HasPrefix(line, "!Rule:") {
+				pattern.rule = strings.TrimSpace(line[6:])
+			}
+			continue
+		}
+		for x, cell := range line {
+			switch cell {
+			case 'O', '*':
+				pattern.cells = append(pattern.cells, cellCoord{x, y})
+			case '.':
+			default:
+				return lifePattern{}, fmt.Errorf("unexpected '%c' in plaintext data at line %d", cell, l+1)
+			}
+		}
+		y++
+	}
+	return pattern, nil
+}
+
+func parseLife105Pattern(data string) (lifePattern, error) {
+	var pattern lifePattern
+	xBlock, y := 0, 0
+	for l, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#P"):
+			var yBlock int
+			if _, err := fmt.Sscanf(line[2:], "%d %d", &xBlock, &yBlock); err != nil {
+				return lifePattern{}, fmt.Errorf("malformed block position at line %d", l+1)
+			}
+			y = yBlock
+		case strings.HasPrefix(line, "#N"):
+			pattern.rule = gKnownRules["life"]
+		case strings.HasPrefix(line, "#R"):
+			pattern.rule = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "#") || len(line) == 0:
+			continue
+		default:
+			for x, cell := range line {
+				switch cell {
+				case '*', 'O':
+					pattern.cells = append(pattern.cells, cellCoord{xBlock + x, y})
+				case '.':
+				default:
+					return lifePattern{}, fmt.Errorf("unexpected '%c' in Life 1.05 data at line %d", cell, l+1)
+				}
+			}
+			y++
+		}
+	}
+	return pattern.normalize(), nil
+}
+
+func parseLife106Pattern(data string) (lifePattern, error) {
+	var pattern lifePattern
+	for l, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var cell cellCoord
+		if _, err := fmt.Sscanf(line, "%d %d", &cell.x, &cell.y); err != nil {
+			return lifePattern{}, fmt.Errorf("malformed cell coordinates at line %d", l+1)
+		}
+		pattern.cells = append(pattern.cells, cell)
+	}
+	return pattern.normalize(), nil
+}
+
+func parseRLEPattern(data string, maxWidth, maxHeight int) (lifePattern, error) {
+	var pattern lifePattern
+	headerFound := false
+	x, y := 0, 0
+	runCount := 0
+	for l, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if strings.HasPrefix(line, "#N") {
+				pattern.name = strings.TrimSpace(line[2:])
+			}
+			continue
+		}
+		if !headerFound {
+			if !strings.HasPrefix(line, "x") {
+				return lifePattern{}, fmt.Errorf("RLE header is missing")
+			}
+			for _, field := range strings.Split(line, ",") {
+				keyValue := strings.SplitN(field, "=", 2)
+				if len(keyValue) != 2 {
+					return lifePattern{}, fmt.Errorf("malformed RLE header at line %d", l+1)
+				}
+				if strings.TrimSpace(keyValue[0]) == "rule" {
+					// Golly appends the bounded grid after ':' (e.g. 'B3/S23:T100,100').
+					pattern.rule = strings.TrimSpace(strings.SplitN(keyValue[1], ":", 2)[0])
+				}
+			}
+			headerFound = true
+			continue
+		}
+		for _, tag := range line {
+			if tag >= '0' && tag <= '9' {
+				if runCount = runCount*10 + int(tag-'0'); runCount > maxWidth && runCount > maxHeight {
+					return lifePattern{}, fmt.Errorf("run count too large at line %d", l+1)
+				}
+				continue
+			}
+			if runCount == 0 {
+				runCount = 1
+			}
+			switch {
+			case tag == 'b' || tag == '.':
+				if x += runCount; x > maxWidth {
+					return lifePattern{}, fmt.Errorf("the pattern is wider than %d cells", maxWidth)
+				}
+			case tag == 'o' || (tag >= 'A' && tag <= 'X'):
+				if x+runCount > maxWidth {
+					return lifePattern{}, fmt.Errorf("the pattern is wider than %d cells", maxWidth)
+				} else if y >= maxHeight {
+					return lifePattern{}, fmt.Errorf("the pattern is taller than %d cells", maxHeight)
+				}
+				for ; runCount > 0; runCount-- {
+					pattern.cells = append(pattern.cells, cellCoord{x, y})
+					x++
+				}
+			case tag == '$':
+				if y += runCount; y > maxHeight {
+					return lifePattern{}, fmt.Errorf("the pattern is taller than %d cells", maxHeight)
+				}
+				x = 0
+			case tag == '!':
+				return pattern, nil
+			case tag == ' ' || tag == '\t' || tag == '\r':
+				continue
+			default:
+				return lifePattern{}, fmt.Errorf("unexpected '%c' in RLE data at line %d", tag, l+1)
+			}
+			runCount = 0
+		}
+	}
+	if !headerFound {
+		return lifePattern{}, fmt.Errorf("RLE header is missing")
+	}
+	return pattern, nil
+}
+
+func (pattern lifePattern) normalize() lifePattern {
+	if len(pattern.cells) == 0 {
+		return pattern
+	}
+	xMin, yMin := pattern.cells[0].x, pattern.cells[0].y
+	for _, cell := range pattern.cells {
+		if cell.x < xMin {
+			xMin = cell.x
+		}
+		if cell.y < yMin {
+			yMin = cell.y
+		}
+	}
+	return pattern.translate(-xMin, -yMin)
+}
+
+func (pattern lifePattern) translate(dx, dy int) lifePattern {
+	translated := lifePattern{name: pattern.name, rule: pattern.rule, cells: make([]cellCoord, len(pattern.cells))}
+	for c, cell := range pattern.cells {
+		translated.cells[c] = cellCoord{cell.x + dx, cell.y + dy}
+	}
+	return translated
+}
+
+func (pattern lifePattern) rotate(degrees int) lifePattern {
+	pattern = pattern.normalize()
+	for ; degrees > 0; degrees -= 90 {
+		pattern = transformPattern(pattern, rotateCell90)
+	}
+	return pattern
+}
+
+func (pattern lifePattern) flipX() lifePattern {
+	return transformPattern(pattern, mirrorCellX)
+}
+
+func (pattern lifePattern) flipY() lifePattern {
+	return transformPattern(pattern, mirrorCellY)
+}
+
+func unitePatterns(patterns ...lifePattern) lifePattern {
+	var united lifePattern
+	isAlive := make(map[cellCoord]bool)
+	for _, pattern := range patterns {
+		if len(united.name) == 0 {
+			united.name = pattern.name
+		}
+		if len(united.rule) == 0 {
+			united.rule = pattern.rule
+		}
+		for _, cell := range pattern.cells {
+			if !isAlive[cell] {
+				isAlive[cell] = true
+				united.cells = append(united.cells, cell)
+			}
+		}
+	}
+	return united
+}
+
+func getPatternFromUniverse(universe lifeUniverse, name string, rule lifeRule) lifePattern {
+	pattern := lifePattern{name: name, rule: rule.String()}
+	universe.forEachAlive(universe.getBoundingBox(), func(x, y int) {
+		pattern.cells = append(pattern.cells, cellCoord{x, y})
+	})
+	sortCells(pattern.cells)
+	return pattern.normalize()
+}
+
+func sortCells(cells []cellCoord) {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].y != cells[j].y {
+			return cells[i].y < cells[j].y
+		}
+		return cells[i].x < cells[j].x
+	})
+}
+
+func (pattern lifePattern) getBoundingBox() image.Rectangle {
+	var boundingBox image.Rectangle
+	for _, c := range pattern.cells {
+		boundingBox = boundingBox.Union(image.Rect(c.x, c.y, c.x+1, c.y+1))
+	}
+	return boundingBox
+}
+
+// Unbounded engines take the pattern anywhere, as long as it is not bigger than the board.
+func checkPatternFits(pattern lifePattern, xNr, yNr int, unbounded bool) error {
+	boundingBox := pattern.getBoundingBox()
+	if boundingBox.Empty() {
+		return nil
+	}
+	board := image.Rect(0, 0, xNr, yNr)
+	if unbounded {
+		if !isCellInRange(boundingBox.Min.X, boundingBox.Min.Y) || !isCellInRange(boundingBox.Max.X, boundingBox.Max.Y) {
+			return fmt.Errorf("the patterns (at %d,%d) are too far from the origin", boundingBox.Min.X, boundingBox.Min.Y)
+		}
+		board = board.Add(boundingBox.Min)
+	}
+	if !boundingBox.In(board) {
+		return fmt.Errorf("the patterns (%dx%d at %d,%d) do not fit the %dx%d board", boundingBox.Dx(),
+			boundingBox.Dy(), boundingBox.Min.X, boundingBox.Min.Y, xNr, yNr)
+	}
+	return nil
+}
+
+func (pattern lifePattern) getSize() (int, int) {
+	var width, height int
+	for _, cell := range pattern.cells {
+		if cell.x >= width {
+			width = cell.x + 1
+		}
+		if cell.y >= height {
+			height = cell.y + 1
+		}
+	}
+	return width, height
+}
+
+func makeRLEPatternData(pattern lifePattern) string {
+	var data string
+	if len(pattern.name) > 0 {
+		data += "#N " + pattern.name + "\n"
+	}
+	width, height := pattern.getSize()
+	data += fmt.Sprintf("x = %d, y = %d, rule = %s\n", width, height, pattern.rule)
+	var line string
+	putRun := func(count int, tag byte) {
+		if count == 0 {
+			return
+		}
+		run := string(tag)
+		if count > 1 {
+			run = fmt.Sprintf("%d%c", count, tag)
+		}
+		if len(line)+len(run) > 70 {
+			data += line + "\n"
+			line = ""
+		}
+		line += run
+	}
+	x, y, aliveNr := 0, 0, 0
+	for _, cell := range pattern.cells {
+		if cell.y != y || cell.x != x+aliveNr {
+			putRun(aliveNr, 'o')
+			x += aliveNr
+			aliveNr = 0
+			if cell.y != y {
+				putRun(cell.y-y, '$')
+				x, y = 0, cell.y
+			}
+			putRun(cell.x-x, 'b')
+			x = cell.x
+		}
+		aliveNr++
+	}
+	putRun(aliveNr, 'o')
+	putRun(1, '!')
+	return data + line + "\n"
+}
+
+func makePlainTextPatternData(pattern lifePattern) string {
+	var data string
+	if len(pattern.name) > 0 {
+		data += "!Name: " + pattern.name + "\n"
+	}
+	data += "!Rule: " + pattern.rule + "\n"
+	width, height := pattern.getSize()
+	rows := make([][]byte, height)
+	for y := range rows {
+		rows[y] = bytes.Repeat([]byte{'.'}, width)
+	}
+	for _, cell := range pattern.cells {
+		rows[cell.y][cell.x] = 'O'
+	}
+	for _, row := range rows {
+		data += string(row) + "\n"
+	}
+	return data
+}
+
+func makeBoardUniverse(xNr, yNr int, rule lifeRule, topology boardTopology, workersNr int) (lifeUniverse, error) {
+	if xNr <= 0 || yNr <= 0 {
+		return nil, fmt.Errorf("the board must have at least one cell")
+	}
+	return &boardUniverse{cells: makeGameBoard(xNr, yNr), rule: rule, topology: topology, workersNr: workersNr}, nil
+}
+
+func (universe *boardUniverse) setAlive(x, y int) {
+	if x > -1 && y > -1 && x < len(universe.cells) && y < len(universe.cells[0]) {
+		universe.cells[x][y] = 1
+	}
+}
+
+func (universe *boardUniverse) isAlive(x, y int) bool {
+	if x < 0 || y < 0 || x >= len(universe.cells) || y >= len(universe.cells[0]) {
+		return false
+	}
+	return ((universe.cells[x][y] & 0x1) == 1) != universe.inverted
+}
+
+// With B0 rules the board may be holding the complement of the generation (see getStepRule()).
+func (universe *boardUniverse) nextGeneration() {
+	var stepRule lifeRule
+	stepRule, universe.inverted = universe.rule.getStepRule(universe.inverted)
+	if universe.workersNr <= 1 {
+		getNextGeneration(universe.cells, stepRule, universe.topology)
+		return
+	}
+	if universe.nextCells == nil {
+		universe.nextCells = makeGameBoard(len(universe.cells), len(universe.cells[0]))
+	}
+	getNextGenerationInParallel(universe.cells, universe.nextCells, stepRule, universe.topology, universe.workersNr)
+	universe.cells, universe.nextCells = universe.nextCells, universe.cells
+}
+
+func (universe *boardUniverse) nextGenerations(n int) {
+	for ; n > 0; n-- {
+		universe.nextGeneration()
+	}
+}
+
+func (universe *boardUniverse) forEachAlive(area image.Rectangle, do func(x, y int)) {
+	area = area.Intersect(image.Rect(0, 0, len(universe.cells), len(universe.cells[0])))
+	for x := area.Min.X; x < area.Max.X; x++ {
+		for y := area.Min.Y; y < area.Max.Y; y++ {
+			if ((universe.cells[x][y] & 0x1) == 1) != universe.inverted {
+				do(x, y)
+			}
+		}
+	}
+}
+
+func (universe *boardUniverse) getBoundingBox() image.Rectangle {
+	var boundingBox image.Rectangle
+	universe.forEachAlive(image.Rect(0, 0, len(universe.cells), len(universe.cells[0])), func(x, y int) {
+		boundingBox = boundingBox.Union(image.Rect(x, y, x+1, y+1))
+	})
+	return boundingBox
+}
+
+func (universe *boardUniverse) clone() lifeUniverse {
+	copied := *universe
+	copied.cells = makeGameBoard(len(universe.cells), len(universe.cells[0]))
+	for x := range universe.cells {
+		copy(copied.cells[x], universe.cells[x])
+	}
+	copied.nextCells = nil
+	return &copied
+}
+
+func makeBitBoardUniverse(xNr, yNr int, rule lifeRule, topology boardTopology, workersNr int) (lifeUniverse, error) {
+	if xNr <= 0 || yNr <= 0 {
+		return nil, fmt.Errorf("the board must have at least one cell")
+	}
+	wordsNr := (xNr + 2 + 63) >> 6
+	universe := &bitBoardUniverse{rows: make([][]uint64, yNr+2),
+		next:      make([][]uint64, yNr+2),
+		rowMask:   make([]uint64, wordsNr),
+		xNr:       xNr,
+		yNr:       yNr,
+		rule:      rule,
+		topology:  topology,
+		workersNr: workersNr}
+	for y := range universe.rows {
+		universe.rows[y] = make([]uint64, wordsNr)
+		universe.next[y] = make([]uint64, wordsNr)
+	}
+	for x := 1; x <= xNr; x++ {
+		universe.rowMask[x>>6] |= 1 << uint(x&63)
+	}
+	return universe, nil
+}
+
+func (universe *bitBoardUniverse) getCell(x, y int) bool {
+	return (universe.rows[y+1][(x+1)>>6]>>uint((x+1)&63))&1 == 1
+}
+
+func (universe *bitBoardUniverse) putCell(x, y int, alive bool) {
+	if alive {
+		universe.rows[y+1][(x+1)>>6] |= 1 << uint((x+1)&63)
+	} else {
+		universe.rows[y+1][(x+1)>>6] &^= 1 << uint((x+1)&63)
+	}
+}
+
+func (universe *bitBoardUniverse) setAlive(x, y int) {
+	if x > -1 && y > -1 && x < universe.xNr && y < universe.yNr {
+		universe.putCell(x, y, true)
+	}
+}
+
+func (universe *bitBoardUniverse) isAlive(x, y int) bool {
+	if x < 0 || y < 0 || x >= universe.xNr || y >= universe.yNr {
+		return false
+	}
+	return universe.getCell(x, y) != universe.inverted
+}
+
+func (universe *bitBoardUniverse) fillHalo() {
+	if universe.topology == planeTopology {
+		return
+	}
+	fill := func(x, y int) {
+		wx, wy, onBoard := universe.topology.wrapCoords(x, y, universe.xNr, universe.yNr)
+		universe.putCell(x, y, onBoard && universe.getCell(wx, wy))
+	}
+	for x := -1; x <= universe.xNr; x++ {
+		fill(x, -1)
+		fill(x, universe.yNr)
+	}
+	for y := 0; y < universe.yNr; y++ {
+		fill(-1, y)
+		fill(universe.xNr, y)
+	}
+}
+
+func halfAdd(a, b uint64) (sum, carry uint64) {
+	return a ^ b, a & b
+}
+
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	t := a ^ b
+	return t ^ c, (a & b) | (t & c)
+}
+
+// Each word holds one bit of 64 neighbour counts, added up by a small adder circuit.
+func (universe *bitBoardUniverse) getNextRow(rule lifeRule, y int) {
+	up, mid, down, next := universe.rows[y-1], universe.rows[y], universe.rows[y+1], universe.next[y]
+	lastWord := len(mid) - 1
+	for w := range mid {
+		var upW, upE, midW, midE, downW, downE uint64
+		upW, midW, downW = up[w]<<1, mid[w]<<1, down[w]<<1
+		upE, midE, downE = up[w]>>1, mid[w]>>1, down[w]>>1
+		if w > 0 {
+			upW |= up[w-1] >> 63
+			midW |= mid[w-1] >> 63
+			downW |= down[w-1] >> 63
+		}
+		if w < lastWord {
+			upE |= up[w+1] << 63
+			midE |= mid[w+1] << 63
+			downE |= down[w+1] << 63
+		}
+		sa, ca := fullAdd(upW, up[w], upE)
+		sb, cb := fullAdd(midW, midE, downW)
+		sc, cc := halfAdd(down[w], downE)
+		c0, cd := fullAdd(sa, sb, sc)
+		t0, t1 := fullAdd(ca, cb, cc)
+		c1, ce := halfAdd(t0, cd)
+		c2, c3 := halfAdd(t1, ce)
+		alive := mid[w]
+		var nextWord uint64
+		for n := uint(0); n < 9; n++ {
+			var cond uint64
+			if rule.survives(int(n)) {
+				cond = alive
+			}
+			if rule.borns(int(n)) {
+				cond |= ^alive
+			}
+			if cond == 0 {
+				continue
+			}
+			eq := ^uint64(0)
+			for b, bit := range []uint64{c0, c1, c2, c3} {
+				if (n>>uint(b))&1 == 1 {
+					eq &= bit
+				} else {
+					eq &^= bit
+				}
+			}
+			nextWord |= eq & cond
+		}
+		next[w] = nextWord & universe.rowMask[w]
+	}
+}
+
+func (universe *bitBoardUniverse) nextGeneration() {
+	var stepRule lifeRule
+	stepRule, universe.inverted = universe.rule.getStepRule(universe.inverted)
+	universe.fillHalo()
+	runOnStripes(universe.yNr, universe.workersNr, func(yFirst, yLast int) {
+		for y := yFirst + 1; y <= yLast; y++ {
+			universe.getNextRow(stepRule, y)
+		}
+	})
+	universe.rows, universe.next = universe.next, universe.rows
+}
+
+func (universe *bitBoardUniverse) nextGenerations(n int) {
+	for ; n > 0; n-- {
+		universe.nextGeneration()
+	}
+}
+
+func (universe *bitBoardUniverse) forEachAlive(area image.Rectangle, do func(x, y int)) {
+	area = area.Intersect(image.Rect(0, 0, universe.xNr, universe.yNr))
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for w, word := range universe.rows[y+1] {
+			if universe.inverted {
+				word = ^word
+			}
+			word &= universe.rowMask[w]
+			for word != 0 {
+				x := w<<6 + bits.TrailingZeros64(word) - 1
+				word &= word - 1
+				if x >= area.Min.X && x < area.Max.X {
+					do(x, y)
+				}
+			}
+		}
+	}
+}
+
+func (universe *bitBoardUniverse) getBoundingBox() image.Rectangle {
+	var boundingBox image.Rectangle
+	universe.forEachAlive(image.Rect(0, 0, universe.xNr, universe.yNr), func(x, y int) {
+		boundingBox = boundingBox.Union(image.Rect(x, y, x+1, y+1))
+	})
+	return boundingBox
+}
+
+func (universe *bitBoardUniverse) clone() lifeUniverse {
+	copied := *universe
+	copied.rows = make([][]uint64, len(universe.rows))
+	copied.next = make([][]uint64, len(universe.next))
+	for y := range universe.rows {
+		copied.rows[y] = append([]uint64{}, universe.rows[y]...)
+		copied.next[y] = make([]uint64, len(universe.next[y]))
+	}
+	return &copied
+}
+
+func makeSparseUniverse(xNr, yNr int, rule lifeRule, topology boardTopology, workersNr int) (lifeUniverse, error) {
+	if topology != planeTopology {
+		return nil, fmt.Errorf("sparse universes are unbounded, thus only the plane topology makes sense")
+	}
+	if rule.borns(0) {
+		return nil, fmt.Errorf("B0 rules would fill up an unbounded universe, use the board engine instead")
+	}
+	return &sparseUniverse{cells: make(map[cellCoord]struct{}), rule: rule}, nil
+}
+
+func (universe *sparseUniverse) setAlive(x, y int) {
+	universe.cells[cellCoord{x, y}] = struct{}{}
+}
+
+func (universe *sparseUniverse) isAlive(x, y int) bool {
+	_, alive := universe.cells[cellCoord{x, y}]
+	return alive
+}
+
+func (universe *sparseUniverse) nextGeneration() {
+	neighbours := make(map[cellCoord]int, len(universe.cells)*3)
+	for c := range universe.cells {
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx != 0 || dy != 0 {
+					neighbours[cellCoord{c.x + dx, c.y + dy}]++
+				}
+			}
+		}
+	}
+	nextCells := make(map[cellCoord]struct{}, len(universe.cells))
+	for c, aliveNeighboursNr := range neighbours {
+		_, alive := universe.cells[c]
+		if (alive && universe.rule.survives(aliveNeighboursNr)) || (!alive && universe.rule.borns(aliveNeighboursNr)) {
+			nextCells[c] = struct{}{}
+		}
+	}
+	if universe.rule.survives(0) {
+		for c := range universe.cells {
+			if _, found := neighbours[c]; !found {
+				nextCells[c] = struct{}{}
+			}
+		}
+	}
+	universe.cells = nextCells
+}
+
+func (universe *sparseUniverse) nextGenerations(n int) {
+	for ; n > 0; n-- {
+		universe.nextGeneration()
+	}
+}
+
+func (universe *sparseUniverse) forEachAlive(area image.Rectangle, do func(x, y int)) {
+	for c := range universe.cells {
+		if (image.Point{c.x, c.y}).In(area) {
+			do(c.x, c.y)
+		}
+	}
+}
+
+func (universe *sparseUniverse) getBoundingBox() image.Rectangle {
+	var boundingBox image.Rectangle
+	for c := range universe.cells {
+		boundingBox = boundingBox.Union(image.Rect(c.x, c.y, c.x+1, c.y+1))
+	}
+	return boundingBox
+}
+
+func (universe *sparseUniverse) clone() lifeUniverse {
+	copied := &sparseUniverse{cells: make(map[cellCoord]struct{}, len(universe.cells)), rule: universe.rule}
+	for c := range universe.cells {
+		copied.cells[c] = struct{}{}
+	}
+	return copied
+}
+
+func makeHashLifeUniverse(xNr, yNr int, rule lifeRule, topology boardTopology, workersNr int) (lifeUniverse, error) {
+	if topology != planeTopology {
+		return nil, fmt.Errorf("hashlife universes are unbounded, thus only the plane topology makes sense")
+	}
+	if rule.borns(0) {
+		return nil, fmt.Errorf("B0 rules would fill up an unbounded universe, use the board engine instead")
+	}
+	universe := &hashLifeUniverse{rule: rule,
+		deadCell:  &hashLifeNode{},
+		aliveCell: &hashLifeNode{population: 1, bounds: image.Rect(0, 0, 1, 1)}}
+	universe.resetCaches()
+	universe.root = universe.getEmptyNode(3)
+	universe.originX, universe.originY = -4, -4
+	return universe, nil
+}
+
+func (universe *hashLifeUniverse) resetCaches() {
+	universe.nodes = make(map[hashLifeNodeKey]*hashLifeNode)
+	universe.results = make(map[hashLifeResultKey]*hashLifeNode)
+	universe.emptyNodes = []*hashLifeNode{universe.deadCell}
+}
+
+func (universe *hashLifeUniverse) join(nw, ne, sw, se *hashLifeNode) *hashLifeNode {
+	key := hashLifeNodeKey{nw, ne, sw, se}
+	if node, ok := universe.nodes[key]; ok {
+		return node
+	}
+	half := 1 << nw.level
+	node := &hashLifeNode{nw: nw, ne: ne, sw: sw, se: se,
+		level:      nw.level + 1,
+		population: nw.population + ne.population + sw.population + se.population,
+		bounds: nw.bounds.Union(ne.bounds.Add(image.Pt(half, 0))).
+			Union(sw.bounds.Add(image.Pt(0, half))).
+			Union(se.bounds.Add(image.Pt(half, half)))}
+	universe.nodes[key] = node
+	return node
+}
+
+func (universe *hashLifeUniverse) getEmptyNode(level uint) *hashLifeNode {
+	for uint(len(universe.emptyNodes)) <= level {
+		e := universe.emptyNodes[len(universe.emptyNodes)-1]
+		universe.emptyNodes = append(universe.emptyNodes, universe.join(e, e, e, e))
+	}
+	return universe.emptyNodes[level]
+}
+
+func (universe *hashLifeUniverse) expand() {
+	root := universe.root
+	if root.level >= gHashLifeMaxLevel {
+		return
+	}
+	e := universe.getEmptyNode(root.level - 1)
+	universe.root = universe.join(universe.join(e, e, e, root.nw), universe.join(e, e, root.ne, e),
+		universe.join(e, root.sw, e, e), universe.join(root.se, e, e, e))
+	universe.originX -= 1 << (root.level - 1)
+	universe.originY -= 1 << (root.level - 1)
+}
+
+func (universe *hashLifeUniverse) getArea() image.Rectangle {
+	size := 1 << universe.root.level
+	return image.Rect(universe.originX, universe.originY, universe.originX+size, universe.originY+size)
+}
+
+func (universe *hashLifeUniverse) setCell(node *hashLifeNode, x, y int) *hashLifeNode {
+	if node.level == 0 {
+		return universe.aliveCell
+	}
+	half := 1 << (node.level - 1)
+	switch {
+	case x < half && y < half:
+		return universe.join(universe.setCell(node.nw, x, y), node.ne, node.sw, node.se)
+	case y < half:
+		return universe.join(node.nw, universe.setCell(node.ne, x-half, y), node.sw, node.se)
+	case x < half:
+		return universe.join(node.nw, node.ne, universe.setCell(node.sw, x, y-half), node.se)
+	}
+	return universe.join(node.nw, node.ne, node.sw, universe.setCell(node.se, x-half, y-half))
+}
+
+func (universe *hashLifeUniverse) setAlive(x, y int) {
+	if !isCellInRange(x, y) {
+		return
+	}
+	for !(image.Point{x, y}).In(universe.getArea()) {
+		universe.expand()
+	}
+	universe.root = universe.setCell(universe.root, x-universe.originX, y-universe.originY)
+}
+
+func (universe *hashLifeUniverse) isAlive(x, y int) bool {
+	if !(image.Point{x, y}).In(universe.getArea()) {
+		return false
+	}
+	x -= universe.originX
+	y -= universe.originY
+	node := universe.root
+	for node.level > 0 && node.population > 0 {
+		half := 1 << (node.level - 1)
+		switch {
+		case x < half && y < half:
+			node = node.nw
+		case y < half:
+			node, x = node.ne, x-half
+		case x < half:
+			node, y = node.sw, y-half
+		default:
+			node, x, y = node.se, x-half, y-half
+		}
+	}
+	return node.population > 0
+}
+
+func (universe *hashLifeUniverse) getCenter(node *hashLifeNode) *hashLifeNode {
+	return universe.join(node.nw.se, node.ne.sw, node.sw.ne, node.se.nw)
+}
+
+func (universe *hashLifeUniverse) getNextBaseGeneration(node *hashLifeNode) *hashLifeNode {
+	var cells [4][4]int
+	for q, quadrant := range []*hashLifeNode{node.nw, node.ne, node.sw, node.se} {
+		for c, cell := range []*hashLifeNode{quadrant.nw, quadrant.ne, quadrant.sw, quadrant.se} {
+			cells[(q&1)*2+(c&1)][(q>>1)*2+(c>>1)] = cell.population
+		}
+	}
+	next := make([]*hashLifeNode, 0, 4)
+	for _, c := range []image.Point{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+		aliveNeighboursNr := 0
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx != 0 || dy != 0 {
+					aliveNeighboursNr += cells[c.X+dx][c.Y+dy]
+				}
+			}
+		}
+		if (cells[c.X][c.Y] == 1 && universe.rule.survives(aliveNeighboursNr)) ||
+			(cells[c.X][c.Y] == 0 && universe.rule.borns(aliveNeighboursNr)) {
+			next = append(next, universe.aliveCell)
+		} else {
+			next = append(next, universe.deadCell)
+		}
+	}
+	return universe.join(next[0], next[1], next[2], next[3])
+}
+
+// Returns the center of node 2^step generations ahead, step must not be greater than node.level - 2.
+func (universe *hashLifeUniverse) advance(node *hashLifeNode, step uint) *hashLifeNode {
+	if node.population == 0 {
+		return universe.getEmptyNode(node.level - 1)
+	}
+	key := hashLifeResultKey{node, step}
+	if result, ok := universe.results[key]; ok {
+		return result
+	}
+	var result *hashLifeNode
+	if node.level == 2 {
+		result = universe.getNextBaseGeneration(node)
+	} else {
+		n := [9]*hashLifeNode{node.nw,
+			universe.join(node.nw.ne, node.ne.nw, node.nw.se, node.ne.sw),
+			node.ne,
+			universe.join(node.nw.sw, node.nw.se, node.sw.nw, node.sw.ne),
+			universe.getCenter(node),
+			universe.join(node.ne.sw, node.ne.se, node.se.nw, node.se.ne),
+			node.sw,
+			universe.join(node.sw.ne, node.se.nw, node.sw.se, node.se.sw),
+			node.se}
+		var m [9]*hashLifeNode
+		innerStep := step
+		if step == node.level-2 {
+			innerStep = step - 1
+			for i := range n {
+				m[i] = universe.advance(n[i], innerStep)
+			}
+		} else {
+			for i := range n {
+				m[i] = universe.getCenter(n[i])
+			}
 		}
+		result = universe.join(universe.advance(universe.join(m[0], m[1], m[3], m[4]), innerStep),
+			universe.advance(universe.join(m[1], m[2], m[4], m[5]), innerStep),
+			universe.advance(universe.join(m[3], m[4], m[6], m[7]), innerStep),
+			universe.advance(universe.join(m[4], m[5], m[7], m[8]), innerStep))
 	}
+	universe.results[key] = result
+	return result
 }
 
-func countAliveNeighboursIter(cells [][]byte, x, y, xNr, yNr int) int {
+func (universe *hashLifeUniverse) isCenteredFor(step uint) bool {
+	root := universe.root
+	return root.level >= step+3 &&
+		root.nw.population == root.nw.se.se.population &&
+		root.ne.population == root.ne.sw.sw.population &&
+		root.sw.population == root.sw.ne.ne.population &&
+		root.se.population == root.se.nw.nw.population
+}
+
+func (universe *hashLifeUniverse) jump(step uint) {
+	for !universe.isCenteredFor(step) && universe.root.level < gHashLifeMaxLevel {
+		universe.expand()
+	}
+	offset := 1 << (universe.root.level - 2)
+	universe.root = universe.advance(universe.root, step)
+	universe.originX += offset
+	universe.originY += offset
+	if len(universe.nodes) > gHashLifeMaxNodes {
+		universe.collectGarbage()
+	}
+}
+
+func (universe *hashLifeUniverse) collectGarbage() {
+	universe.resetCaches()
+	rebuilt := make(map[*hashLifeNode]*hashLifeNode)
+	var rebuild func(node *hashLifeNode) *hashLifeNode
+	rebuild = func(node *hashLifeNode) *hashLifeNode {
+		if node.level == 0 {
+			return node
+		}
+		if r, ok := rebuilt[node]; ok {
+			return r
+		}
+		r := universe.join(rebuild(node.nw), rebuild(node.ne), rebuild(node.sw), rebuild(node.se))
+		rebuilt[node] = r
+		return r
+	}
+	universe.root = rebuild(universe.root)
+}
+
+func (universe *hashLifeUniverse) nextGeneration() {
+	universe.jump(0)
+}
+
+func (universe *hashLifeUniverse) nextGenerations(n int) {
+	for step := uint(0); n > 0; step, n = step+1, n>>1 {
+		if n&1 == 1 && step+3 <= gHashLifeMaxLevel {
+			universe.jump(step)
+		} else if n&1 == 1 {
+			for j := 0; j < 1<<(step+3-gHashLifeMaxLevel); j++ {
+				universe.jump(gHashLifeMaxLevel - 3)
+			}
+		}
+	}
+}
+
+func (universe *hashLifeUniverse) forEachAlive(area image.Rectangle, do func(x, y int)) {
+	var visit func(node *hashLifeNode, x, y int)
+	visit = func(node *hashLifeNode, x, y int) {
+		if node.population == 0 || !node.bounds.Add(image.Pt(x, y)).Overlaps(area) {
+			return
+		}
+		if node.level == 0 {
+			do(x, y)
+			return
+		}
+		half := 1 << (node.level - 1)
+		visit(node.nw, x, y)
+		visit(node.ne, x+half, y)
+		visit(node.sw, x, y+half)
+		visit(node.se, x+half, y+half)
+	}
+	visit(universe.root, universe.originX, universe.originY)
+}
+
+func (universe *hashLifeUniverse) getBoundingBox() image.Rectangle {
+	return universe.root.bounds.Add(image.Pt(universe.originX, universe.originY))
+}
+
+func (universe *hashLifeUniverse) clone() lifeUniverse {
+	copied, _ := makeHashLifeUniverse(0, 0, universe.rule, planeTopology, 1)
+	universe.forEachAlive(universe.getBoundingBox(), copied.setAlive)
+	return copied
+}
+
+func countAliveNeighboursIter(cells [][]byte, x, y, xNr, yNr int, topology boardTopology) int {
 	if x < 0 || y < 0 || x >= xNr || y >= yNr {
-		return 0
+		var onBoard bool
+		if x, y, onBoard = topology.wrapCoords(x, y, xNr, yNr); !onBoard {
+			return 0
+		}
 	}
 	return int(cells[x][y] & 0x1)
 }
 
-func countAliveNeighbours(cells [][]byte, x, y int) int {
+func countAliveNeighbours(cells [][]byte, x, y int, topology boardTopology) int {
 	xNr := len(cells)
 	yNr := len(cells[0])
-	return countAliveNeighboursIter(cells, x-1, y-1, xNr, yNr) +
-		countAliveNeighboursIter(cells, x, y-1, xNr, yNr) +
-		countAliveNeighboursIter(cells, x+1, y-1, xNr, yNr) +
-		countAliveNeighboursIter(cells, x-1, y, xNr, yNr) +
-		countAliveNeighboursIter(cells, x+1, y, xNr, yNr) +
-		countAliveNeighboursIter(cells, x-1, y+1, xNr, yNr) +
-		countAliveNeighboursIter(cells, x, y+1, xNr, yNr) +
-		countAliveNeighboursIter(cells, x+1, y+1, xNr, yNr)
+	return countAliveNeighboursIter(cells, x-1, y-1, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x, y-1, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x+1, y-1, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x-1, y, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x+1, y, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x-1, y+1, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x, y+1, xNr, yNr, topology) +
+		countAliveNeighboursIter(cells, x+1, y+1, xNr, yNr, topology)
 }
 
-func getNextGeneration(cells [][]byte) {
+func getNextGeneration(cells [][]byte, rule lifeRule, topology boardTopology) {
 	xNr := len(cells)
 	yNr := len(cells[0])
 	for x := 0; x < xNr; x++ {
 		for y := 0; y < yNr; y++ {
 			// INFO(Rafael): Just because I am a lazy person...
-			aliveNeighboursNr := countAliveNeighbours(cells, x, y)
-			itWillLiveOrReproduct := ((cells[x][y]&1) == 1 && (aliveNeighboursNr == 2 || aliveNeighboursNr == 3)) ||
-				((cells[x][y]&1) == 0 && (aliveNeighboursNr == 3))
+			aliveNeighboursNr := countAliveNeighbours(cells, x, y, topology)
+			itWillLiveOrReproduct := ((cells[x][y]&1) == 1 && rule.survives(aliveNeighboursNr)) ||
+				((cells[x][y]&1) == 0 && rule.borns(aliveNeighboursNr))
 			if itWillLiveOrReproduct {
 				cells[x][y] |= 0x2 // ...see?!
 			}
@@ -772,20 +5164,307 @@ func getNextGeneration(cells [][]byte) {
 	}
 }
 
-func getColor(colorName string) color.Color {
+// A stripe can not write into cells while its neighbours read their halo rows from it, thus every
+// stripe writes its own rows into nextCells.
+func getNextGenerationInParallel(cells, nextCells [][]byte, rule lifeRule, topology boardTopology, workersNr int) {
+	xNr := len(cells)
+	runOnStripes(len(cells[0]), workersNr, func(yFirst, yLast int) {
+		for x := 0; x < xNr; x++ {
+			for y := yFirst; y < yLast; y++ {
+				aliveNeighboursNr := countAliveNeighbours(cells, x, y, topology)
+				if ((cells[x][y]&1) == 1 && rule.survives(aliveNeighboursNr)) ||
+					((cells[x][y]&1) == 0 && rule.borns(aliveNeighboursNr)) {
+					nextCells[x][y] = 1
+				} else {
+					nextCells[x][y] = 0
+				}
+			}
+		}
+	})
+}
+
+func runOnStripes(rowsNr, workersNr int, do func(yFirst, yLast int)) {
+	if workersNr > rowsNr {
+		workersNr = rowsNr
+	}
+	if workersNr <= 1 {
+		do(0, rowsNr)
+		return
+	}
+	stripesNr := workersNr * 4
+	if stripesNr > rowsNr {
+		stripesNr = rowsNr
+	}
+	stripes := make(chan int, stripesNr)
+	for s := 0; s < stripesNr; s++ {
+		stripes <- s
+	}
+	close(stripes)
+	var workers sync.WaitGroup
+	workers.Add(workersNr)
+	for w := 0; w < workersNr; w++ {
+		go func() {
+			defer workers.Done()
+			for s := range stripes {
+				do(s*rowsNr/stripesNr, (s+1)*rowsNr/stripesNr)
+			}
+		}()
+	}
+	workers.Wait()
+}
+
+func parseTopology(name string, xNr, yNr int) (boardTopology, error) {
+	topology, ok := gAvailTopologies[name]
+	if !ok {
+		return planeTopology, fmt.Errorf("'%s' is not a known topology", name)
+	}
+	if topology == sphereTopology && xNr != yNr {
+		return planeTopology, fmt.Errorf("sphere topology requires a square board")
+	}
+	return topology, nil
+}
+
+func (topology boardTopology) wrapCoords(x, y, xNr, yNr int) (wx, wy int, onBoard bool) {
+	xOut := x < 0 || x >= xNr
+	yOut := y < 0 || y >= yNr
+	switch topology {
+	case torusTopology:
+		return (x + xNr) % xNr, (y + yNr) % yNr, true
+	case kleinXTopology:
+		if xOut {
+			x = (x + xNr) % xNr
+			y = yNr - 1 - y
+		}
+		return x, (y + yNr) % yNr, true
+	case kleinYTopology:
+		if yOut {
+			y = (y + yNr) % yNr
+			x = xNr - 1 - x
+		}
+		return (x + xNr) % xNr, y, true
+	case crossSurfaceTopology:
+		if xOut && yOut {
+			return x, y, false
+		}
+		if xOut {
+			return (x + xNr) % xNr, yNr - 1 - y, true
+		}
+		return xNr - 1 - x, (y + yNr) % yNr, true
+	case sphereTopology:
+		if xOut && yOut {
+			return x, y, false
+		}
+		switch {
+		case x < 0:
+			return y, 0, true
+		case x >= xNr:
+			return y, yNr - 1, true
+		case y < 0:
+			return 0, x, true
+		}
+		return xNr - 1, x, true
+	}
+	return x, y, false
+}
+
+func parseRule(ruleString string) (lifeRule, error) {
+	var rule lifeRule
+	if known, ok := gKnownRules[strings.ToLower(ruleString)]; ok {
+		ruleString = known
+	}
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(ruleString)), "/")
+	if len(parts) != 2 {
+		return rule, fmt.Errorf("'%s' is not a Life-like rulestring (e.g. B3/S23)", ruleString)
+	}
+	if !strings.HasPrefix(parts[0], "B") && !strings.HasPrefix(parts[0], "S") &&
+		!strings.HasPrefix(parts[1], "B") && !strings.HasPrefix(parts[1], "S") {
+		parts[0] = "S" + parts[0]
+		parts[1] = "B" + parts[1]
+	}
+	var hasBirth, hasSurvival bool
+	for _, p := range parts {
+		if len(p) == 0 {
+			return rule, fmt.Errorf("'%s' is not a Life-like rulestring (e.g. B3/S23)", ruleString)
+		}
+		var set *uint16
+		switch p[0] {
+		case 'B':
+			set = &rule.birth
+			hasBirth = true
+		case 'S':
+			set = &rule.survival
+			hasSurvival = true
+		default:
+			return rule, fmt.Errorf("'%s' is not a Life-like rulestring (e.g. B3/S23)", ruleString)
+		}
+		for _, n := range p[1:] {
+			if n < '0' || n > '8' {
+				return rule, fmt.Errorf("'%c' is not a valid neighbour count in rule '%s'", n, ruleString)
+			}
+			*set |= 1 << uint(n-'0')
+		}
+	}
+	if !hasBirth || !hasSurvival {
+		return rule, fmt.Errorf("'%s' must define both birth and survival conditions", ruleString)
+	}
+	return rule, nil
+}
+
+func (rule lifeRule) String() string {
+	str := "B"
+	for n := 0; n < 9; n++ {
+		if rule.borns(n) {
+			str += strconv.Itoa(n)
+		}
+	}
+	str += "/S"
+	for n := 0; n < 9; n++ {
+		if rule.survives(n) {
+			str += strconv.Itoa(n)
+		}
+	}
+	return str
+}
+
+func (rule lifeRule) borns(aliveNeighboursNr int) bool {
+	return (rule.birth>>uint(aliveNeighboursNr))&1 == 1
+}
+
+func (rule lifeRule) survives(aliveNeighboursNr int) bool {
+	return (rule.survival>>uint(aliveNeighboursNr))&1 == 1
+}
+
+// Under a B0 rule the whole (infinite) background turns alive, thus the complement of those generations
+// is kept and stepped by an equivalent rule.
+func (rule lifeRule) getStepRule(inverted bool) (lifeRule, bool) {
+	if !rule.borns(0) {
+		return rule, false
+	}
+	if !inverted {
+		return lifeRule{^rule.birth & 0x1FF, ^rule.survival & 0x1FF}, true
+	}
+	var stepRule lifeRule
+	for n := 0; n < 9; n++ {
+		if rule.survives(8-n) != rule.survives(8) {
+			stepRule.birth |= 1 << uint(n)
+		}
+		if rule.borns(8-n) != rule.survives(8) {
+			stepRule.survival |= 1 << uint(n)
+		}
+	}
+	return stepRule, rule.survives(8)
+}
+
+func makeRandom(seed string) (*rand.Rand, int64, error) {
+	var seedValue int64
+	if len(seed) == 0 {
+		seedValue = time.Now().UTC().UnixNano()
+	} else {
+		var err error
+		if seedValue, err = strconv.ParseInt(seed, 10, 64); err != nil {
+			return nil, 0, err
+		}
+	}
+	return rand.New(rand.NewSource(seedValue)), seedValue, nil
+}
+
+func getRandomFromOptions() (*rand.Rand, error) {
+	seed := getOption("seed", "")
+	random, seedValue, err := makeRandom(seed)
+	if err != nil {
+		return nil, fmt.Errorf("option seed must be a valid integer")
+	}
+	if len(seed) == 0 {
+		fmt.Fprintf(os.Stderr, "INFO: seed = %d.\n", seedValue)
+	}
+	return random, nil
+}
+
+func getColor(colorName string, random *rand.Rand) color.Color {
+	cl, err := parseColor(colorName, random)
+	if err != nil {
+		return color.Black
+	}
+	return cl
+}
+
+func parseColor(colorName string, random *rand.Rand) (color.Color, error) {
+	colorName = strings.ToLower(strings.TrimSpace(colorName))
 	if colorName == "any" || colorName == "random" {
 		var r, g, b uint8
-		for l := 0; l <= rand.Int()%((rand.Int()%10)+1); l++ {
-			r = (uint8(rand.Int()) + g)
-			g = (uint8(rand.Int()) + r + b)
-			b = (uint8(rand.Int()) + r + g)
+		for l := 0; l <= random.Int()%((random.Int()%10)+1); l++ {
+			r = (uint8(random.Int()) + g)
+			g = (uint8(random.Int()) + r + b)
+			b = (uint8(random.Int()) + r + g)
 		}
-		return color.RGBA{r, g, b, 0xFF}
+		return color.RGBA{r, g, b, 0xFF}, nil
 	}
 	if cl, ok := gAvailColors[colorName]; ok {
-		return cl
+		return cl, nil
+	}
+	var rgb [3]uint64
+	var err error
+	switch {
+	case strings.HasPrefix(colorName, "#") && (len(colorName) == 7 || len(colorName) == 4):
+		digitsNr := (len(colorName) - 1) / 3
+		for c := range rgb {
+			rgb[c], err = strconv.ParseUint(colorName[1+c*digitsNr:1+(c+1)*digitsNr], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a valid hexadecimal color", colorName)
+			}
+			if digitsNr == 1 {
+				rgb[c] *= 0x11
+			}
+		}
+	case strings.HasPrefix(colorName, "rgb(") && strings.HasSuffix(colorName, ")"):
+		components := strings.Split(colorName[4:len(colorName)-1], ",")
+		if len(components) != 3 {
+			return nil, fmt.Errorf("'%s' must have three components", colorName)
+		}
+		for c := range rgb {
+			rgb[c], err = strconv.ParseUint(strings.TrimSpace(components[c]), 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("the components of '%s' must be integers between 0 and 255", colorName)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("'%s' is not a known color", colorName)
+	}
+	return color.RGBA{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), 0xFF}, nil
+}
+
+func loadPalette(filePath string, random *rand.Rand) ([]color.Color, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var palette []color.Color
+	for l, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, ";") {
+			continue
+		}
+		cl, err := parseColor(line, random)
+		if err != nil {
+			return nil, fmt.Errorf("%v at line %d", err, l+1)
+		}
+		palette = append(palette, cl)
+	}
+	if len(palette) < 2 || len(palette) > gAgeGradientLength+1 {
+		return nil, fmt.Errorf("a palette must have between 2 and %d colors", gAgeGradientLength+1)
+	}
+	return palette, nil
+}
+
+func getOptions(option string) []string {
+	var values []string
+	optionLabel := "--" + option + "="
+	for _, o := range os.Args[2:] {
+		if strings.HasPrefix(o, optionLabel) {
+			values = append(values, o[len(optionLabel):])
+		}
 	}
-	return color.Black
+	return values
 }
 
 func getOption(option, defaultValue string) string {
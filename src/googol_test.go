@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+var gTestRules = []string{"B3/S23", "B36/S23", "B036/S125", "B0123478/S34678", "B0/S8"}
+
+func makeTestUniverse(t testing.TB, engine string, xNr, yNr int, ruleString, topologyName string,
+	workersNr int, seed int64) lifeUniverse {
+	rule, err := parseRule(ruleString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	universe, err := gAvailEngines[engine](xNr, yNr, rule, gAvailTopologies[topologyName], workersNr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := rand.New(rand.NewSource(seed))
+	for x := 0; x < xNr; x++ {
+		for y := 0; y < yNr; y++ {
+			if random.Float64() < 0.4 {
+				universe.setAlive(x, y)
+			}
+		}
+	}
+	return universe
+}
+
+func getTestBoardSize(topologyName string) (int, int) {
+	if topologyName == "sphere" {
+		return 67, 67
+	}
+	return 71, 43
+}
+
+func checkSameCells(t *testing.T, generation, xNr, yNr int, expected, universe lifeUniverse) {
+	t.Helper()
+	for x := 0; x < xNr; x++ {
+		for y := 0; y < yNr; y++ {
+			if expected.isAlive(x, y) != universe.isAlive(x, y) {
+				t.Fatalf("generation %d: cell (%d, %d) should be %v", generation, x, y, expected.isAlive(x, y))
+			}
+		}
+	}
+}
+
+func TestBitBoardMatchesBoard(t *testing.T) {
+	for topologyName := range gAvailTopologies {
+		for _, ruleString := range gTestRules {
+			t.Run(topologyName+"/"+ruleString, func(t *testing.T) {
+				xNr, yNr := getTestBoardSize(topologyName)
+				board := makeTestUniverse(t, "board", xNr, yNr, ruleString, topologyName, 1, 42)
+				bitBoard := makeTestUniverse(t, "bitboard", xNr, yNr, ruleString, topologyName, 1, 42)
+				for g := 0; g < 60; g++ {
+					checkSameCells(t, g, xNr, yNr, board, bitBoard)
+					board.nextGeneration()
+					bitBoard.nextGeneration()
+				}
+			})
+		}
+	}
+}
+
+func TestParallelMatchesSerial(t *testing.T) {
+	for _, engine := range []string{"board", "bitboard"} {
+		for topologyName := range gAvailTopologies {
+			for _, ruleString := range gTestRules {
+				t.Run(engine+"/"+topologyName+"/"+ruleString, func(t *testing.T) {
+					xNr, yNr := getTestBoardSize(topologyName)
+					serial := makeTestUniverse(t, engine, xNr, yNr, ruleString, topologyName, 1, 7)
+					var parallel []lifeUniverse
+					for _, workersNr := range []int{2, 4, 8} {
+						parallel = append(parallel,
+							makeTestUniverse(t, engine, xNr, yNr, ruleString, topologyName, workersNr, 7))
+					}
+					for g := 0; g < 40; g++ {
+						for _, universe := range parallel {
+							checkSameCells(t, g, xNr, yNr, serial, universe)
+							universe.nextGeneration()
+						}
+						serial.nextGeneration()
+					}
+				})
+			}
+		}
+	}
+}
+
+func getAliveCells(universe lifeUniverse) map[cellCoord]bool {
+	cells := make(map[cellCoord]bool)
+	universe.forEachAlive(universe.getBoundingBox(), func(x, y int) {
+		cells[cellCoord{x, y}] = true
+	})
+	return cells
+}
+
+func TestHashLifeMatchesSparse(t *testing.T) {
+	for _, ruleString := range []string{"B3/S23", "B36/S23"} {
+		for _, generationStep := range []int{1, 3, 8, 13, 64} {
+			t.Run(fmt.Sprintf("%s/%d", ruleString, generationStep), func(t *testing.T) {
+				sparse := makeTestUniverse(t, "sparse", 32, 32, ruleString, "plane", 1, 3)
+				hashLife := makeTestUniverse(t, "hashlife", 32, 32, ruleString, "plane", 1, 3)
+				for g := 0; g < 600; g += generationStep {
+					expected, cells := getAliveCells(sparse), getAliveCells(hashLife)
+					if len(expected) != len(cells) {
+						t.Fatalf("generation %d: %d cells alive, %d expected", g, len(cells), len(expected))
+					}
+					for cell := range expected {
+						if !cells[cell] {
+							t.Fatalf("generation %d: cell (%d, %d) should be alive", g, cell.x, cell.y)
+						}
+					}
+					sparse.nextGenerations(generationStep)
+					hashLife.nextGenerations(generationStep)
+				}
+			})
+		}
+	}
+}
+
+func benchmarkEngine(b *testing.B, engine string) {
+	universe := makeTestUniverse(b, engine, 500, 500, "B3/S23", "plane", 1, 42)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		universe.nextGeneration()
+	}
+}
+
+func BenchmarkBoard500(b *testing.B) {
+	benchmarkEngine(b, "board")
+}
+
+func BenchmarkBitBoard500(b *testing.B) {
+	benchmarkEngine(b, "bitboard")
+}
+
+func TestParseRLEPatternLimits(t *testing.T) {
+	for _, data := range []string{"x = 0, y = 0\n999999999o!", "x = 0, y = 0\n99999999999999999999999b!",
+		"x = 0, y = 0\n11o!", "x = 0, y = 0\n10$o!", "x = 0, y = 0\n11$!"} {
+		if _, err := parseRLEPattern(data, 10, 10); err == nil {
+			t.Errorf("%q should not fit a 10x10 board", data)
+		}
+	}
+	for _, data := range []string{"x = 10, y = 10\n10o$10b$9bo!", "x = 10, y = 10\n9$10o$!"} {
+		if _, err := parseRLEPattern(data, 10, 10); err != nil {
+			t.Errorf("%q should fit a 10x10 board: %v", data, err)
+		}
+	}
+}
+
+func TestCheckPatternFits(t *testing.T) {
+	glider := lifePattern{cells: []cellCoord{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}}
+	if err := checkPatternFits(glider.translate(7, 7), 10, 10, false); err != nil {
+		t.Error(err)
+	}
+	if err := checkPatternFits(glider.translate(8, 0), 10, 10, false); err == nil {
+		t.Error("a glider at 8,0 should not fit a 10x10 board")
+	}
+	if err := checkPatternFits(glider.translate(-100, 100), 10, 10, true); err != nil {
+		t.Error(err)
+	}
+	far := lifePattern{cells: []cellCoord{{0, 0}, {1000000000, 0}}}
+	if err := checkPatternFits(far, 10, 10, true); err == nil {
+		t.Error("a 1000000001x1 pattern should not fit a 10x10 board")
+	}
+}
+
+func TestMakeSoupFits(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	if _, err := makeSoup("100x100@0.5", 30, 30, random); err == nil {
+		t.Error("a 100x100 soup should not fit a 30x30 board")
+	}
+	soup, err := makeSoup("30x20@0.5:D2", 30, 30, random)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checkPatternFits(soup, 30, 30, false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLifeHistoryForgetsOldStates(t *testing.T) {
+	rule, _ := parseRule("B3/S23")
+	universe, _ := makeSparseUniverse(0, 0, rule, planeTopology, 1)
+	for _, cell := range []cellCoord{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		universe.setAlive(cell.x, cell.y)
+	}
+	history := newLifeHistory(4)
+	for g := 0; g < 4; g++ {
+		if cycle := history.findCycle(universe, g); cycle != nil {
+			t.Fatalf("generation %d: unexpected cycle %v", g, cycle)
+		}
+		universe.nextGeneration()
+	}
+	cycle := history.findCycle(universe, 4)
+	if cycle == nil || cycle.first != 0 || cycle.period != 4 || cycle.displacement != image.Pt(1, 1) {
+		t.Fatalf("the glider should repeat every 4 generations translated by (1,1), got %v", cycle)
+	}
+	if len(history.keys) > 4 || len(history.states) > 4 {
+		t.Errorf("%d states kept, up to 4 expected", len(history.states))
+	}
+	short := newLifeHistory(3)
+	for g := 0; g <= 8; g++ {
+		if cycle := short.findCycle(universe, g); cycle != nil {
+			t.Fatalf("a period of 4 should not be found within 3 frames, got %v", cycle)
+		}
+		universe.nextGeneration()
+	}
+}
+
+func TestGetSoupAshSettles(t *testing.T) {
+	rule, _ := parseRule("B3/S23")
+	block := []cellCoord{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+	glider := []cellCoord{{11, 10}, {12, 11}, {10, 12}, {11, 12}, {12, 12}}
+	ash, stable := getSoupAsh(lifePattern{cells: append(block, glider...)}, rule, 1000)
+	if !stable || len(ash.cells) != 9 {
+		t.Errorf("a block and a glider flying away should settle, got %v with %d cells", stable, len(ash.cells))
+	}
+	rPentomino := lifePattern{cells: []cellCoord{{1, 0}, {2, 0}, {0, 1}, {1, 1}, {1, 2}}}
+	if _, stable = getSoupAsh(rPentomino, rule, 500); stable {
+		t.Error("the R-pentomino should not settle within 500 generations")
+	}
+	if _, stable = getSoupAsh(rPentomino, rule, 1500); !stable {
+		t.Error("the R-pentomino should settle within 1500 generations")
+	}
+}